@@ -0,0 +1,149 @@
+package layr8
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// AckState is the settlement of an inbound message, as reported by
+// Message.AckState.
+type AckState int
+
+const (
+	// AckStatePending means the message has not been settled yet. A
+	// manual-ack message still pending when its handler returns without
+	// error is reported to the ErrorHandler as ErrMissingAck.
+	AckStatePending AckState = iota
+
+	// AckStateAcked means the message was acknowledged, either by Ack or
+	// automatically for handlers without WithManualAck.
+	AckStateAcked
+
+	// AckStateNacked means Nack scheduled the message to be handled again.
+	AckStateNacked
+
+	// AckStateRejected means Reject acknowledged the message and refused it
+	// with a problem report.
+	AckStateRejected
+)
+
+var ackStateNames = map[AckState]string{
+	AckStatePending:  "pending",
+	AckStateAcked:    "acked",
+	AckStateNacked:   "nacked",
+	AckStateRejected: "rejected",
+}
+
+func (s AckState) String() string {
+	if name, ok := ackStateNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("AckState(%d)", s)
+}
+
+// errNacked is counted as a failed attempt towards Config.MaxDeliveryAttempts
+// when a handler calls Nack.
+var errNacked = errors.New("message nacked by handler")
+
+// ackControl settles an inbound message exactly once.
+type ackControl struct {
+	mu     sync.Mutex
+	state  AckState
+	settle func(state AckState, delay time.Duration, reason string)
+}
+
+// Nack declines the message for now and has the SDK run the handler again
+// after delay. The message stays unacknowledged meanwhile, so if the agent
+// stops first, the cloud-node redelivers it. Each Nack counts as a failed
+// attempt towards Config.MaxDeliveryAttempts.
+// Only meaningful when the handler was registered with WithManualAck().
+func (m *Message) Nack(delay time.Duration) {
+	m.settle(AckStateNacked, delay, "")
+}
+
+// Reject permanently refuses the message: it is acknowledged, so the
+// cloud-node does not redeliver it, and the sender receives an
+// "e.p.msg.rejected" problem report with reason as the comment.
+// Only meaningful when the handler was registered with WithManualAck().
+func (m *Message) Reject(reason string) {
+	m.settle(AckStateRejected, 0, reason)
+}
+
+// Acked reports whether the message has been acknowledged, by Ack or
+// automatically. Rejected messages are acknowledged too, but report false.
+func (m *Message) Acked() bool {
+	return m.AckState() == AckStateAcked
+}
+
+// AckState reports how the message has been settled so far. Messages not
+// delivered to a handler are always AckStatePending.
+func (m *Message) AckState() AckState {
+	if m.ack == nil {
+		return AckStatePending
+	}
+	m.ack.mu.Lock()
+	defer m.ack.mu.Unlock()
+	return m.ack.state
+}
+
+// settle moves a pending message to state and lets the client act on it.
+func (m *Message) settle(state AckState, delay time.Duration, reason string) {
+	if m.ack == nil {
+		return
+	}
+	m.ack.mu.Lock()
+	if m.ack.state != AckStatePending {
+		m.ack.mu.Unlock()
+		return
+	}
+	m.ack.state = state
+	fn := m.ack.settle
+	m.ack.mu.Unlock()
+
+	if fn != nil {
+		fn(state, delay, reason)
+	}
+}
+
+// newAckControl returns the ack control for msg about to be handled by
+// entry. Handlers without WithManualAck are acknowledged right away.
+func (c *Client) newAckControl(entry handlerEntry, msg *Message) *ackControl {
+	if !entry.manualAck {
+		c.ack(msg.ID)
+		return &ackControl{state: AckStateAcked}
+	}
+	return &ackControl{settle: func(state AckState, delay time.Duration, reason string) {
+		switch state {
+		case AckStateAcked:
+			c.ack(msg.ID)
+		case AckStateRejected:
+			c.deliveries.forget(msg.ID)
+			c.ack(msg.ID)
+			c.replyProblem(msg, &ProblemReportError{Code: "e.p.msg.rejected", Comment: reason})
+		case AckStateNacked:
+			if c.handlerFailed(msg, errNacked) {
+				return // dead-lettered instead
+			}
+			time.AfterFunc(delay, func() { c.redispatch(msg) })
+		}
+	}}
+}
+
+// redispatch hands a nacked message to its handler again, as if the
+// cloud-node had redelivered it. If the client is shutting down or the
+// handler is gone, the message is left for the cloud-node to redeliver.
+func (c *Client) redispatch(msg *Message) {
+	if c.ctx.Err() != nil || c.State() == StateDraining {
+		return
+	}
+	entry, ok := c.registry.lookup(msg.Type)
+	if !ok {
+		return
+	}
+	retry := *msg
+	retry.ack = nil
+	retry.claimed = false // released when the nacked run ended
+	c.dispatch(entry, &retry)
+}
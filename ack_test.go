@@ -0,0 +1,151 @@
+package layr8
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestMessage_Nack_RetriesLocally(t *testing.T) {
+	fake := &fakeTransport{}
+	client := newTestClient(t, fake, Config{}, nil)
+	runs := make(chan time.Time, 10)
+	attempt := 0 // runs are serialized by the retry delay
+	client.Handle(dispatchTypeA, func(msg *Message) (*Message, error) {
+		attempt++
+		defer func() { runs <- time.Now() }()
+		if attempt == 1 {
+			msg.Nack(50 * time.Millisecond)
+			if msg.AckState() != AckStateNacked || msg.Acked() {
+				t.Errorf("AckState() = %v, want nacked", msg.AckState())
+			}
+			return nil, nil
+		}
+		msg.Ack()
+		return nil, nil
+	}, WithManualAck())
+	connectTestClient(t, client)
+
+	fake.handler(inboundPayload("m1", dispatchTypeA, ""))
+	first := <-runs
+	if acks := fake.ackedIDs(); len(acks) != 0 {
+		t.Errorf("acks = %v, want none after Nack", acks)
+	}
+
+	select {
+	case second := <-runs:
+		if second.Sub(first) < 50*time.Millisecond {
+			t.Errorf("retried after %v, want at least the Nack delay", second.Sub(first))
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for the retry")
+	}
+	waitForAcks(t, fake, "m1")
+	if n := len(fake.sentIDs()); n != 0 {
+		t.Errorf("sent %d messages, want no problem report for a Nack", n)
+	}
+}
+
+func TestMessage_Nack_CountsTowardsMaxDeliveryAttempts(t *testing.T) {
+	fake := &fakeTransport{}
+	sink := NewMemoryDeadLetterSink()
+	client := newTestClient(t, fake, Config{MaxDeliveryAttempts: 2, DeadLetters: sink}, nil)
+	runs := make(chan string, 10)
+	client.Handle(dispatchTypeA, func(msg *Message) (*Message, error) {
+		runs <- msg.ID
+		msg.Nack(0)
+		return nil, nil
+	}, WithManualAck())
+	connectTestClient(t, client)
+
+	fake.handler(inboundPayload("m1", dispatchTypeA, ""))
+	<-runs
+	<-runs // retried once, then dead-lettered
+	waitForAcks(t, fake, "m1")
+
+	letters, _ := sink.List(context.Background())
+	if len(letters) != 1 || letters[0].MessageID != "m1" || letters[0].Attempts != 2 {
+		t.Errorf("dead letters = %+v, want m1 after 2 attempts", letters)
+	}
+	select {
+	case <-runs:
+		t.Error("handler ran again after the message was dead-lettered")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestMessage_Reject(t *testing.T) {
+	fake := &fakeTransport{}
+	client := newTestClient(t, fake, Config{}, nil)
+	client.Handle(dispatchTypeA, func(msg *Message) (*Message, error) {
+		msg.Reject("unknown order {1}")
+		msg.Ack() // ignored: already settled
+		return &Message{Type: dispatchTypeB}, nil
+	}, WithManualAck())
+	connectTestClient(t, client)
+
+	fake.handler(inboundPayload("m1", dispatchTypeA, ""))
+	prob := sentProblem(t, fake)
+	if prob.Code != "e.p.msg.rejected" || prob.Comment != "unknown order {1}" {
+		t.Errorf("problem report = %+v, want e.p.msg.rejected with the reason", prob)
+	}
+	if acks := fake.ackedIDs(); !slices.Equal(acks, []string{"m1"}) {
+		t.Errorf("acks = %v, want m1 acked once", acks)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if n := len(fake.sentIDs()); n != 1 {
+		t.Errorf("sent %d messages, want only the problem report", n)
+	}
+}
+
+func TestClient_ManualAck_MissingAckReported(t *testing.T) {
+	fake := &fakeTransport{}
+	errs := make(chan SDKError, 10)
+	client := newTestClient(t, fake, Config{}, func(err SDKError) { errs <- err })
+	client.Handle(dispatchTypeA, func(msg *Message) (*Message, error) {
+		return nil, nil // forgot msg.Ack()
+	}, WithManualAck())
+	connectTestClient(t, client)
+
+	fake.handler(inboundPayload("m1", dispatchTypeA, ""))
+	select {
+	case err := <-errs:
+		if err.Kind != ErrMissingAck || err.MessageID != "m1" {
+			t.Errorf("reported %v for %q, want ErrMissingAck for m1", err.Kind, err.MessageID)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for ErrMissingAck")
+	}
+	if acks := fake.ackedIDs(); len(acks) != 0 {
+		t.Errorf("acks = %v, want the message left unacknowledged", acks)
+	}
+}
+
+func TestClient_AutoAck_AckState(t *testing.T) {
+	fake := &fakeTransport{}
+	client := newTestClient(t, fake, Config{}, nil)
+	states := make(chan AckState, 1)
+	client.Handle(dispatchTypeA, func(msg *Message) (*Message, error) {
+		msg.Nack(0) // no effect: already acknowledged
+		states <- msg.AckState()
+		return nil, nil
+	})
+	connectTestClient(t, client)
+
+	fake.handler(inboundPayload("m1", dispatchTypeA, ""))
+	if state := <-states; state != AckStateAcked {
+		t.Errorf("AckState() = %v, want acked", state)
+	}
+}
+
+func waitForAcks(t *testing.T, fake *fakeTransport, want ...string) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !slices.Equal(fake.ackedIDs(), want) && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if acks := fake.ackedIDs(); !slices.Equal(acks, want) {
+		t.Fatalf("acks = %v, want %v", acks, want)
+	}
+}
@@ -0,0 +1,99 @@
+package layr8
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// AckBatching coalesces acknowledgments, so that many messages are
+// acknowledged in one frame instead of one frame each. The zero value sends
+// every ack immediately.
+type AckBatching struct {
+	// Window is the longest an ack waits for others to join its batch.
+	// 0 disables batching. A few milliseconds is usually enough.
+	Window time.Duration
+
+	// MaxSize sends a batch as soon as it holds this many message IDs.
+	// Default: 100.
+	MaxSize int
+}
+
+func (p AckBatching) withDefaults() AckBatching {
+	if p.MaxSize == 0 {
+		p.MaxSize = 100
+	}
+	return p
+}
+
+func (p AckBatching) validate() error {
+	if p.Window < 0 {
+		return fmt.Errorf("AckBatching.Window must not be negative")
+	}
+	if p.MaxSize < 0 {
+		return fmt.Errorf("AckBatching.MaxSize must not be negative")
+	}
+	return nil
+}
+
+// ackBatcher collects message IDs to acknowledge and sends them in batches.
+type ackBatcher struct {
+	policy AckBatching
+	send   func(ids []string)
+
+	mu      sync.Mutex
+	pending []string
+	timer   *time.Timer // runs flush at the end of the window; nil when idle
+}
+
+func newAckBatcher(policy AckBatching, send func(ids []string)) *ackBatcher {
+	return &ackBatcher{policy: policy.withDefaults(), send: send}
+}
+
+// add acknowledges id, immediately or as part of the next batch.
+func (b *ackBatcher) add(id string) {
+	if b.policy.Window == 0 {
+		b.send([]string{id})
+		return
+	}
+
+	b.mu.Lock()
+	b.pending = append(b.pending, id)
+	if len(b.pending) < b.policy.MaxSize {
+		if b.timer == nil {
+			b.timer = time.AfterFunc(b.policy.Window, b.flush)
+		}
+		b.mu.Unlock()
+		return
+	}
+	ids := b.take()
+	b.mu.Unlock()
+	b.send(ids)
+}
+
+// flush sends the pending batch, if any.
+func (b *ackBatcher) flush() {
+	b.mu.Lock()
+	ids := b.take()
+	b.mu.Unlock()
+	if len(ids) > 0 {
+		b.send(ids)
+	}
+}
+
+// take empties the batch and returns its IDs. Must be called with b.mu held.
+func (b *ackBatcher) take() []string {
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	ids := b.pending
+	b.pending = nil
+	return ids
+}
+
+// ack acknowledges an inbound message to the cloud-node, batched according
+// to Config.AckBatching.
+func (c *Client) ack(id string) {
+	c.acks.add(id)
+}
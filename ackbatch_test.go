@@ -0,0 +1,153 @@
+package layr8
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// batchRecorder records the batches an ackBatcher sends.
+type batchRecorder struct {
+	mu      sync.Mutex
+	batches [][]string
+}
+
+func (r *batchRecorder) send(ids []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.batches = append(r.batches, ids)
+}
+
+func (r *batchRecorder) get() [][]string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.batches)
+}
+
+func TestAckBatcher_CoalescesWithinWindow(t *testing.T) {
+	r := &batchRecorder{}
+	b := newAckBatcher(AckBatching{Window: 20 * time.Millisecond}, r.send)
+	b.add("1")
+	b.add("2")
+	b.add("3")
+	if got := r.get(); len(got) != 0 {
+		t.Fatalf("sent %v before the window ended", got)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	got := r.get()
+	if len(got) != 1 || !slices.Equal(got[0], []string{"1", "2", "3"}) {
+		t.Errorf("batches = %v, want [[1 2 3]]", got)
+	}
+}
+
+func TestAckBatcher_MaxSize(t *testing.T) {
+	r := &batchRecorder{}
+	b := newAckBatcher(AckBatching{Window: time.Hour, MaxSize: 2}, r.send)
+	b.add("1")
+	b.add("2")
+	b.add("3")
+	if got := r.get(); len(got) != 1 || !slices.Equal(got[0], []string{"1", "2"}) {
+		t.Fatalf("batches = %v, want [[1 2]] once MaxSize is reached", got)
+	}
+
+	b.flush()
+	b.flush() // nothing left: no empty batch
+	if got := r.get(); len(got) != 2 || !slices.Equal(got[1], []string{"3"}) {
+		t.Errorf("batches = %v, want [3] sent by flush", got)
+	}
+}
+
+func TestAckBatcher_Immediate(t *testing.T) {
+	r := &batchRecorder{}
+	b := newAckBatcher(AckBatching{}, r.send)
+	b.add("1")
+	b.add("2")
+	if got := r.get(); len(got) != 2 {
+		t.Errorf("batches = %v, want each ack sent on its own", got)
+	}
+}
+
+func TestClient_AckBatching_OneFrame(t *testing.T) {
+	mock, _, wsURL := setupMockServer(t)
+	client, _ := NewClient(Config{
+		NodeURL:     wsURL,
+		APIKey:      "test-key",
+		AgentDID:    "did:web:alice",
+		AckBatching: AckBatching{Window: 50 * time.Millisecond},
+	}, discardErrors)
+	handled := make(chan string, 3)
+	client.Handle(dispatchTypeA, func(msg *Message) (*Message, error) {
+		handled <- msg.ID
+		return nil, nil
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Connect(ctx); err != nil {
+		t.Fatalf("Connect() error: %v", err)
+	}
+	defer client.Close()
+
+	for _, id := range []string{"m1", "m2", "m3"} {
+		mock.sendToClient(phoenixMessage{Topic: "plugin:lobby", Event: "message", Payload: inboundPayload(id, dispatchTypeA, "")})
+	}
+	for range 3 {
+		<-handled
+	}
+	time.Sleep(200 * time.Millisecond)
+
+	var frames [][]string
+	for _, msg := range mock.getReceived() {
+		if msg.Event == "ack" {
+			var p struct {
+				IDs []string `json:"ids"`
+			}
+			json.Unmarshal(msg.Payload, &p)
+			frames = append(frames, p.IDs)
+		}
+	}
+	if len(frames) != 1 || !slices.Equal(frames[0], []string{"m1", "m2", "m3"}) {
+		t.Errorf("ack frames = %v, want one frame acking m1, m2 and m3", frames)
+	}
+}
+
+func TestClient_AckBatching_FlushOnClose(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		stop func(*Client) error
+	}{
+		{"Close", (*Client).Close},
+		{"Shutdown", func(c *Client) error { return c.Shutdown(context.Background()) }},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			fake := &fakeTransport{}
+			client := newTestClient(t, fake, Config{AckBatching: AckBatching{Window: time.Hour}}, nil)
+			done := make(chan struct{}, 2)
+			client.Handle(dispatchTypeA, func(msg *Message) (*Message, error) {
+				done <- struct{}{}
+				return nil, nil
+			})
+			connectTestClient(t, client)
+
+			fake.handler(inboundPayload("m1", dispatchTypeA, ""))
+			fake.handler(inboundPayload("m2", dispatchTypeA, ""))
+			<-done
+			<-done
+			if acks := fake.ackedIDs(); len(acks) != 0 {
+				t.Fatalf("acks = %v, want them held for the window", acks)
+			}
+
+			if err := tc.stop(client); err != nil {
+				t.Fatalf("%s() error: %v", tc.name, err)
+			}
+			fake.mu.Lock()
+			defer fake.mu.Unlock()
+			if !slices.Equal(fake.acks, []string{"m1", "m2"}) || fake.ackFrames != 1 {
+				t.Errorf("acks = %v in %d frames, want [m1 m2] in one", fake.acks, fake.ackFrames)
+			}
+		})
+	}
+}
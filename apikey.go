@@ -0,0 +1,89 @@
+package layr8
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CredentialProvider supplies the API key used to authenticate with the
+// cloud-node. It is consulted on every WebSocket dial (including reconnects)
+// and every REST request, so a rotated key takes effect without restarting
+// the process or dropping the current connection.
+//
+// Implementations must be safe for concurrent use and should be cheap to call.
+type CredentialProvider interface {
+	APIKey(ctx context.Context) (string, error)
+}
+
+// CredentialProviderFunc adapts a function to the CredentialProvider interface.
+type CredentialProviderFunc func(ctx context.Context) (string, error)
+
+// APIKey calls f(ctx).
+func (f CredentialProviderFunc) APIKey(ctx context.Context) (string, error) {
+	return f(ctx)
+}
+
+// StaticCredentials returns a CredentialProvider that always returns key.
+// It is used for Config.APIKey when no Config.Credentials is set.
+func StaticCredentials(key string) CredentialProvider {
+	return CredentialProviderFunc(func(context.Context) (string, error) {
+		return key, nil
+	})
+}
+
+// EnvCredentials returns a CredentialProvider that reads the API key from the
+// environment variable name on every call.
+func EnvCredentials(name string) CredentialProvider {
+	return CredentialProviderFunc(func(context.Context) (string, error) {
+		key := os.Getenv(name)
+		if key == "" {
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+		return key, nil
+	})
+}
+
+// FileCredentials returns a CredentialProvider that reads the API key from the
+// file at path (surrounding whitespace is trimmed). The file is re-read
+// whenever its modification time or size changes, so replacing it — e.g. a
+// Kubernetes secret volume update — rotates the key.
+func FileCredentials(path string) CredentialProvider {
+	return &fileCredentials{path: path}
+}
+
+type fileCredentials struct {
+	path string
+
+	mu      sync.Mutex
+	key     string
+	modTime time.Time
+	size    int64
+}
+
+func (f *fileCredentials) APIKey(context.Context) (string, error) {
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return "", fmt.Errorf("read API key file: %w", err)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.key != "" && info.ModTime().Equal(f.modTime) && info.Size() == f.size {
+		return f.key, nil
+	}
+
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		return "", fmt.Errorf("read API key file: %w", err)
+	}
+	key := strings.TrimSpace(string(data))
+	if key == "" {
+		return "", fmt.Errorf("API key file %s is empty", f.path)
+	}
+	f.key, f.modTime, f.size = key, info.ModTime(), info.Size()
+	return key, nil
+}
@@ -0,0 +1,159 @@
+package layr8
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestStaticCredentials(t *testing.T) {
+	key, err := StaticCredentials("k1").APIKey(context.Background())
+	if err != nil || key != "k1" {
+		t.Errorf("APIKey() = %q, %v; want %q, nil", key, err, "k1")
+	}
+}
+
+func TestEnvCredentials(t *testing.T) {
+	p := EnvCredentials("LAYR8_TEST_ROTATING_KEY")
+
+	t.Setenv("LAYR8_TEST_ROTATING_KEY", "")
+	if _, err := p.APIKey(context.Background()); err == nil {
+		t.Error("APIKey() should fail when the variable is unset")
+	}
+
+	t.Setenv("LAYR8_TEST_ROTATING_KEY", "k1")
+	if key, _ := p.APIKey(context.Background()); key != "k1" {
+		t.Errorf("APIKey() = %q, want %q", key, "k1")
+	}
+	t.Setenv("LAYR8_TEST_ROTATING_KEY", "k2")
+	if key, _ := p.APIKey(context.Background()); key != "k2" {
+		t.Errorf("APIKey() after rotation = %q, want %q", key, "k2")
+	}
+}
+
+func TestFileCredentials_Rotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "api-key")
+	if err := os.WriteFile(path, []byte("key-one\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	p := FileCredentials(path)
+
+	if key, err := p.APIKey(context.Background()); err != nil || key != "key-one" {
+		t.Fatalf("APIKey() = %q, %v; want %q, nil", key, err, "key-one")
+	}
+
+	if err := os.WriteFile(path, []byte("key-two\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	// Same size as before: bump the mtime so the change is detected on
+	// filesystems with coarse timestamps.
+	future := time.Now().Add(time.Minute)
+	os.Chtimes(path, future, future)
+
+	if key, _ := p.APIKey(context.Background()); key != "key-two" {
+		t.Errorf("APIKey() after rotation = %q, want %q", key, "key-two")
+	}
+
+	os.Remove(path)
+	if _, err := p.APIKey(context.Background()); err == nil {
+		t.Error("APIKey() should fail once the file is gone")
+	}
+}
+
+func TestPhoenixChannel_UsesRotatedKeyOnReconnect(t *testing.T) {
+	var mu sync.Mutex
+	var keys []string
+
+	mock := newMockServer()
+	mock.onMsg = func(msg phoenixMessage) {
+		if msg.Event == "phx_join" {
+			mock.sendToClient(phoenixMessage{
+				JoinRef: msg.Ref, Ref: msg.Ref, Topic: msg.Topic, Event: "phx_reply",
+				Payload: json.RawMessage(`{"status":"ok","response":{}}`),
+			})
+		}
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		keys = append(keys, r.URL.Query().Get("api_key"))
+		mu.Unlock()
+		mock.handler(w, r)
+	}))
+	defer server.Close()
+
+	var current atomic.Value
+	current.Store("key-one")
+
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/plugin_socket/websocket"
+	ch := NewPhoenixTransport(Config{
+		NodeURL:  wsURL,
+		AgentDID: "did:web:test",
+		Credentials: CredentialProviderFunc(func(context.Context) (string, error) {
+			return current.Load().(string), nil
+		}),
+		ReconnectPolicy: ReconnectPolicy{InitialDelay: 10 * time.Millisecond},
+	}).(*phoenixChannel)
+
+	reconnected := make(chan struct{}, 1)
+	ch.OnReconnect(func() { reconnected <- struct{}{} })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := ch.Connect(ctx, nil); err != nil {
+		t.Fatalf("Connect() error: %v", err)
+	}
+	defer ch.Close()
+
+	// Rotating the key does not disturb the live connection.
+	current.Store("key-two")
+
+	mock.mu.Lock()
+	mock.conn.Close()
+	mock.mu.Unlock()
+
+	select {
+	case <-reconnected:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for reconnect")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(keys) != 2 || keys[0] != "key-one" || keys[1] != "key-two" {
+		t.Errorf("handshake keys = %v, want [key-one key-two]", keys)
+	}
+}
+
+func TestRestClient_UsesCurrentKey(t *testing.T) {
+	var got atomic.Value
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got.Store(r.Header.Get("x-api-key"))
+		w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	var current atomic.Value
+	current.Store("key-one")
+	rc := newRestClient(srv.URL, CredentialProviderFunc(func(context.Context) (string, error) {
+		return current.Load().(string), nil
+	}), netConfig{})
+
+	for _, want := range []string{"key-one", "key-two"} {
+		current.Store(want)
+		var result map[string]any
+		if err := rc.get(context.Background(), "/", &result); err != nil {
+			t.Fatalf("get() error: %v", err)
+		}
+		if got.Load() != want {
+			t.Errorf("x-api-key = %v, want %q", got.Load(), want)
+		}
+	}
+}
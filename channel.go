@@ -5,7 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"log/slog"
-	"net"
+	"net/http"
 	"net/url"
 	"strings"
 	"sync"
@@ -83,23 +83,41 @@ func unmarshalPhoenixMsg(data []byte) (phoenixMessage, error) {
 	return msg, nil
 }
 
-// phoenixChannel implements the transport interface using WebSocket/Phoenix Channels.
+// phoenixChannel implements the Transport interface using WebSocket/Phoenix Channels.
 type phoenixChannel struct {
-	wsURL    string
-	apiKey   string
-	agentDID string
-	topic    string
+	wsURL       string    // node currently connected to (or being dialed); protected by mu
+	nodes       *nodePool // candidate node URLs for connect and reconnect
+	credentials CredentialProvider
+	agentDID    string
+	topic       string
 
 	conn *websocket.Conn
 	mu   sync.Mutex // protects conn writes, refCounter, and reconnecting
 
 	refCounter   int
-	joinRef      string
-	protocols    []string // stored from connect() for reconnect
+	joinRef      string   // ref of the current join; protected by mu
+	protocols    []string // joined protocols, reused on reconnect; protected by mu
 	reconnecting bool     // true while reconnect loop is running
 
+	reconnectPolicy ReconnectPolicy
+	bo              *backoff  // backoff for the current outage; reset after a stable connection
+	attempts        int       // failed reconnect attempts in the current outage
+	outageStart     time.Time // when the current outage began
+	connectedAt     time.Time // when the current connection was established
+
+	heartbeat HeartbeatPolicy
+	hbRef     string    // ref of the outstanding heartbeat, "" once answered
+	hbSentAt  time.Time // when the outstanding heartbeat was sent
+	hbMissed  int       // consecutive heartbeats without a reply
+	dropErr   error     // reason for a connection closed by the client side (e.g. missed heartbeats)
+
+	net             netConfig // TLS, proxy and dialer settings
+	netErr          error     // invalid network settings, returned by Connect
+	apiKeyPlacement APIKeyPlacement
+
+	joinMu      sync.Mutex // serializes joins, which share pendingJoin
 	pendingJoin chan json.RawMessage
-	pendingRefs sync.Map // ref → chan serverReply
+	pendingRefs sync.Map // ref → chan pendingReply
 
 	msgHandler   func(payload []byte)
 	disconnectFn func(error)
@@ -112,73 +130,181 @@ type phoenixChannel struct {
 
 func newPhoenixChannel(wsURL, apiKey, agentDID string) *phoenixChannel {
 	return &phoenixChannel{
-		wsURL:    wsURL,
-		apiKey:   apiKey,
-		agentDID: agentDID,
-		topic:    fmt.Sprintf("plugins:%s", agentDID),
-		done:     make(chan struct{}),
+		wsURL:       wsURL,
+		nodes:       newNodePool([]string{wsURL}, SelectOrdered),
+		credentials: StaticCredentials(apiKey),
+		agentDID:    agentDID,
+		topic:       fmt.Sprintf("plugins:%s", agentDID),
+		done:        make(chan struct{}),
+
+		reconnectPolicy: ReconnectPolicy{}.withDefaults(),
+		heartbeat:       HeartbeatPolicy{}.withDefaults(),
 	}
 }
 
-func (c *phoenixChannel) connect(ctx context.Context, protocols []string) error {
+// Connect tries each configured node once, in the order chosen by the node
+// selection strategy, and returns the last error if none could be joined.
+func (c *phoenixChannel) Connect(ctx context.Context, protocols []string) error {
+	if c.netErr != nil {
+		return c.netErr
+	}
+	c.mu.Lock()
 	c.protocols = protocols
-	return c.dial(ctx)
+	c.mu.Unlock()
+	var err error
+	for _, nodeURL := range c.nodes.order() {
+		err = c.dial(ctx, nodeURL)
+		c.nodes.report(nodeURL, err == nil)
+		if err == nil || ctx.Err() != nil {
+			return err
+		}
+	}
+	return err
+}
+
+// NodeURL returns the URL of the node the channel is connected to.
+func (c *phoenixChannel) NodeURL() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.wsURL
+}
+
+// dialNext dials the node picked by the node pool and records the outcome.
+func (c *phoenixChannel) dialNext(ctx context.Context) (string, error) {
+	nodeURL := c.nodes.pick()
+	err := c.dial(ctx, nodeURL)
+	c.nodes.report(nodeURL, err == nil)
+	return nodeURL, err
 }
 
 // dial establishes the WebSocket connection, joins the channel, and starts
-// the read loop and heartbeat. Used by both initial connect() and reconnect.
-func (c *phoenixChannel) dial(ctx context.Context) error {
-	// Build URL with API key
-	u, err := url.Parse(c.wsURL)
+// the read loop and heartbeat. Used by both initial Connect() and reconnect.
+func (c *phoenixChannel) dial(ctx context.Context, nodeURL string) error {
+	c.mu.Lock()
+	c.wsURL = nodeURL
+	c.mu.Unlock()
+
+	// Build URL; the API key goes in the query, a header, or the join payload.
+	u, err := url.Parse(nodeURL)
 	if err != nil {
 		return fmt.Errorf("parse URL: %w", err)
 	}
+	// The key is fetched on every dial so a rotated key is used on reconnect.
+	apiKey, err := c.credentials.APIKey(ctx)
+	if err != nil {
+		return &ConnectionError{URL: nodeURL, Reason: fmt.Sprintf("get API key: %v", err)}
+	}
 	q := u.Query()
-	q.Set("api_key", c.apiKey)
+	header := http.Header{}
+	switch c.apiKeyPlacement {
+	case APIKeyInQuery:
+		q.Set("api_key", apiKey)
+	case APIKeyInHeader:
+		header.Set("x-api-key", apiKey)
+	case APIKeyInBearer:
+		header.Set("Authorization", "Bearer "+apiKey)
+	}
 	q.Set("vsn", "2.0.0")
 	u.RawQuery = q.Encode()
 
 	// Connect WebSocket
-	// Use a custom dialer that resolves *.localhost to loopback (RFC 6761).
-	// Go's net package doesn't implement this, unlike curl and browsers.
 	dialer := websocket.Dialer{
 		HandshakeTimeout: 10 * time.Second,
-		NetDialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
-			host, port, err := net.SplitHostPort(addr)
-			if err == nil && isLocalhost(host) {
-				addr = net.JoinHostPort("127.0.0.1", port)
-			}
-			return (&net.Dialer{}).DialContext(ctx, network, addr)
-		},
+		NetDialContext:   c.net.dial(),
+		Proxy:            c.net.proxy,
+		TLSClientConfig:  c.net.tlsConfig,
 	}
-	conn, _, err := dialer.DialContext(ctx, u.String(), nil)
+	conn, _, err := dialer.DialContext(ctx, u.String(), header)
 	if err != nil {
-		return &ConnectionError{URL: c.wsURL, Reason: err.Error()}
+		return &ConnectionError{URL: nodeURL, Reason: err.Error()}
 	}
 
 	c.mu.Lock()
 	c.conn = conn
 	c.refCounter = 0
+	c.hbRef = ""
+	c.hbMissed = 0
+	c.dropErr = nil
 	c.mu.Unlock()
 
 	// Start reader goroutine
 	go c.readLoop()
 
 	// Send phx_join
-	if err := c.join(ctx, c.protocols); err != nil {
+	c.mu.Lock()
+	protocols := c.protocols
+	c.mu.Unlock()
+	if err := c.join(ctx, nodeURL, protocols, apiKey); err != nil {
+		// Abandon the connection first so its read loop exits quietly
+		// instead of reporting a drop and starting a reconnect.
+		c.mu.Lock()
+		if c.conn == conn {
+			c.conn = nil
+		}
+		c.mu.Unlock()
 		conn.Close()
 		return err
 	}
 
+	c.mu.Lock()
+	c.connectedAt = time.Now()
+	c.mu.Unlock()
+
 	// Start heartbeat
-	go c.heartbeatLoop()
+	go c.heartbeatLoop(conn)
+
+	return nil
+}
+
+// UpdateProtocols rejoins the channel on the current socket with new
+// payload types. The node replaces the previous join with the new one;
+// messages already in flight on the old join are still read and handled, and
+// its closing phx_close/phx_error is ignored. If the rejoin is rejected, the
+// previous protocols are restored and the connection is recycled through the
+// reconnect path, since the node has already dropped the old join.
+func (c *phoenixChannel) UpdateProtocols(ctx context.Context, protocols []string) error {
+	c.mu.Lock()
+	old := c.protocols
+	c.protocols = protocols
+	conn, nodeURL := c.conn, c.wsURL
+	live := conn != nil && !c.reconnecting
+	c.mu.Unlock()
+	if !live {
+		return nil // used by the next join
+	}
+
+	var apiKey string
+	if c.apiKeyPlacement == APIKeyInJoinPayload {
+		var err error
+		if apiKey, err = c.credentials.APIKey(ctx); err != nil {
+			c.mu.Lock()
+			c.protocols = old
+			c.mu.Unlock()
+			return &ConnectionError{URL: nodeURL, Reason: fmt.Sprintf("get API key: %v", err)}
+		}
+	}
 
+	if err := c.join(ctx, nodeURL, protocols, apiKey); err != nil {
+		c.mu.Lock()
+		c.protocols = old
+		if c.conn == conn {
+			c.dropErr = fmt.Errorf("update protocols: %w", err)
+		}
+		c.mu.Unlock()
+		conn.Close() // readLoop sees the error and reconnects with the old protocols
+		return err
+	}
 	return nil
 }
 
-func (c *phoenixChannel) join(ctx context.Context, protocols []string) error {
+func (c *phoenixChannel) join(ctx context.Context, nodeURL string, protocols []string, apiKey string) error {
+	c.joinMu.Lock()
+	defer c.joinMu.Unlock()
+
 	ref := c.nextRef()
+	c.mu.Lock()
 	c.joinRef = ref
+	c.mu.Unlock()
 
 	joinParams := map[string]interface{}{
 		"payload_types": protocols,
@@ -194,6 +320,10 @@ func (c *phoenixChannel) join(ctx context.Context, protocols []string) error {
 		},
 	}
 
+	if c.apiKeyPlacement == APIKeyInJoinPayload {
+		joinParams["api_key"] = apiKey
+	}
+
 	payload, _ := json.Marshal(joinParams)
 
 	msg := phoenixMessage{
@@ -228,9 +358,9 @@ func (c *phoenixChannel) join(ctx context.Context, protocols []string) error {
 		if reply.Status != "ok" {
 			reason := reply.Response.Reason
 			if reason != "" {
-				return &ConnectionError{URL: c.wsURL, Reason: reason}
+				return &ConnectionError{URL: nodeURL, Reason: reason}
 			}
-			return &ConnectionError{URL: c.wsURL, Reason: fmt.Sprintf("join rejected: %s", reply.Status)}
+			return &ConnectionError{URL: nodeURL, Reason: fmt.Sprintf("join rejected: %s", reply.Status)}
 		}
 		if reply.Response.DID != "" {
 			c.assignedDIDVal = reply.Response.DID
@@ -241,10 +371,10 @@ func (c *phoenixChannel) join(ctx context.Context, protocols []string) error {
 	}
 }
 
-func (c *phoenixChannel) send(ctx context.Context, event string, payload []byte) (serverReply, error) {
+func (c *phoenixChannel) Send(ctx context.Context, event string, payload []byte) (ServerReply, error) {
 	ref := c.nextRef()
 
-	replyCh := make(chan serverReply, 1)
+	replyCh := make(chan pendingReply, 1)
 	c.pendingRefs.Store(ref, replyCh)
 	defer c.pendingRefs.Delete(ref)
 
@@ -255,18 +385,18 @@ func (c *phoenixChannel) send(ctx context.Context, event string, payload []byte)
 		Payload: payload,
 	}
 	if err := c.writeMsg(msg); err != nil {
-		return serverReply{}, err
+		return ServerReply{}, err
 	}
 
 	select {
-	case reply := <-replyCh:
-		return reply, nil
+	case p := <-replyCh:
+		return p.reply, p.err
 	case <-ctx.Done():
-		return serverReply{}, ctx.Err()
+		return ServerReply{}, ctx.Err()
 	}
 }
 
-func (c *phoenixChannel) sendFireAndForget(event string, payload []byte) error {
+func (c *phoenixChannel) SendFireAndForget(event string, payload []byte) error {
 	msg := phoenixMessage{
 		Ref:     c.nextRef(),
 		Topic:   c.topic,
@@ -276,30 +406,30 @@ func (c *phoenixChannel) sendFireAndForget(event string, payload []byte) error {
 	return c.writeMsg(msg)
 }
 
-func (c *phoenixChannel) sendAck(ids []string) error {
+func (c *phoenixChannel) SendAck(ids []string) error {
 	payload, _ := json.Marshal(map[string]interface{}{
 		"ids": ids,
 	})
-	return c.sendFireAndForget("ack", payload)
+	return c.SendFireAndForget("ack", payload)
 }
 
-func (c *phoenixChannel) setMessageHandler(fn func(payload []byte)) {
+func (c *phoenixChannel) SetMessageHandler(fn func(payload []byte)) {
 	c.msgHandler = fn
 }
 
-func (c *phoenixChannel) onDisconnect(fn func(error)) {
+func (c *phoenixChannel) OnDisconnect(fn func(error)) {
 	c.disconnectFn = fn
 }
 
-func (c *phoenixChannel) onReconnect(fn func()) {
+func (c *phoenixChannel) OnReconnect(fn func()) {
 	c.reconnectFn = fn
 }
 
-func (c *phoenixChannel) assignedDID() string {
+func (c *phoenixChannel) AssignedDID() string {
 	return c.assignedDIDVal
 }
 
-func (c *phoenixChannel) close() error {
+func (c *phoenixChannel) Close() error {
 	select {
 	case <-c.done:
 		return nil // already closed
@@ -349,6 +479,17 @@ func (c *phoenixChannel) readLoop() {
 				return
 			default:
 				// Connection dropped — reject pending refs and start reconnect.
+				// Prefer the reason recorded when we closed the connection ourselves.
+				c.mu.Lock()
+				if c.conn != conn {
+					c.mu.Unlock()
+					return // abandoned after a failed join, or already replaced
+				}
+				if c.dropErr != nil {
+					err = c.dropErr
+					c.dropErr = nil
+				}
+				c.mu.Unlock()
 				c.rejectPendingRefs()
 				if c.disconnectFn != nil {
 					c.disconnectFn(err)
@@ -367,8 +508,9 @@ func (c *phoenixChannel) readLoop() {
 	}
 }
 
-// reconnectLoop attempts to re-establish the connection with exponential backoff.
-// It runs until the connection is restored or close() is called.
+// reconnectLoop attempts to re-establish the connection according to the
+// reconnect policy. It runs until the connection is restored, the policy
+// gives up, or Close() is called.
 func (c *phoenixChannel) reconnectLoop(initialErr error) {
 	c.mu.Lock()
 	if c.reconnecting {
@@ -380,13 +522,32 @@ func (c *phoenixChannel) reconnectLoop(initialErr error) {
 		c.conn.Close()
 		c.conn = nil
 	}
+	policy := c.reconnectPolicy
+	// Only a connection that stayed up for StableAfter starts a fresh outage;
+	// otherwise keep backing off from where the previous outage left off.
+	if c.bo == nil {
+		c.bo = policy.newBackoff()
+		c.outageStart = time.Now()
+	} else if time.Since(c.connectedAt) >= policy.StableAfter {
+		c.bo.reset()
+		c.attempts = 0
+		c.outageStart = time.Now()
+	}
+	bo := c.bo
 	c.mu.Unlock()
 
-	bo := newBackoff(1*time.Second, 30*time.Second)
-
+	lastErr := initialErr
 	for {
+		c.mu.Lock()
+		attempts, outageStart := c.attempts, c.outageStart
+		c.mu.Unlock()
+		if policy.exhausted(attempts, outageStart) {
+			c.giveUp(attempts, lastErr)
+			return
+		}
+
 		delay := bo.next()
-		slog.Info("reconnecting", "delay", delay, "url", c.wsURL)
+		slog.Info("reconnecting", "delay", delay, "attempt", attempts+1)
 
 		select {
 		case <-c.done:
@@ -407,19 +568,21 @@ func (c *phoenixChannel) reconnectLoop(initialErr error) {
 		c.mu.Unlock()
 
 		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-		err := c.dial(ctx)
+		nodeURL, err := c.dialNext(ctx)
 		cancel()
 
 		if err != nil {
 			c.mu.Lock()
 			c.reconnecting = true
+			c.attempts++
 			c.mu.Unlock()
-			slog.Warn("reconnect failed", "error", err, "url", c.wsURL)
+			lastErr = err
+			slog.Warn("reconnect failed", "error", err, "url", nodeURL)
 			continue
 		}
 
 		// Success — reconnecting is already false
-		slog.Info("reconnected", "url", c.wsURL)
+		slog.Info("reconnected", "url", nodeURL)
 		if c.reconnectFn != nil {
 			c.reconnectFn()
 		}
@@ -427,12 +590,33 @@ func (c *phoenixChannel) reconnectLoop(initialErr error) {
 	}
 }
 
-// rejectPendingRefs cancels all in-flight send() calls waiting for server replies.
+// giveUp stops reconnecting once the policy is exhausted. The channel stays
+// disconnected, so writes fail with ErrNotConnected.
+func (c *phoenixChannel) giveUp(attempts int, lastErr error) {
+	c.mu.Lock()
+	c.reconnecting = false
+	c.mu.Unlock()
+
+	err := fmt.Errorf("%w after %d attempts: %v", ErrReconnectGaveUp, attempts, lastErr)
+	slog.Error("reconnect gave up", "error", err)
+	if c.reconnectPolicy.OnGiveUp != nil {
+		c.reconnectPolicy.OnGiveUp(err)
+	}
+}
+
+// pendingReply is what an in-flight Send() receives: the server's reply, or
+// ErrConnectionLost when the connection dropped first.
+type pendingReply struct {
+	reply ServerReply
+	err   error
+}
+
+// rejectPendingRefs cancels all in-flight Send() calls waiting for server replies.
 func (c *phoenixChannel) rejectPendingRefs() {
 	c.pendingRefs.Range(func(key, value interface{}) bool {
-		ch := value.(chan serverReply)
+		ch := value.(chan pendingReply)
 		select {
-		case ch <- serverReply{Status: "error", Reason: "disconnected"}:
+		case ch <- pendingReply{err: ErrConnectionLost}:
 		default:
 		}
 		c.pendingRefs.Delete(key)
@@ -443,11 +627,17 @@ func (c *phoenixChannel) rejectPendingRefs() {
 func (c *phoenixChannel) handleInbound(msg phoenixMessage) {
 	switch msg.Event {
 	case "phx_reply":
+		// Heartbeat reply
+		if msg.Topic == "phoenix" {
+			c.handleHeartbeatReply(msg.Ref)
+			return
+		}
+
 		// Join reply
 		c.mu.Lock()
-		ch := c.pendingJoin
+		ch, joinRef := c.pendingJoin, c.joinRef
 		c.mu.Unlock()
-		if ch != nil && msg.Ref == c.joinRef {
+		if ch != nil && msg.Ref == joinRef {
 			select {
 			case ch <- msg.Payload:
 			default:
@@ -457,7 +647,7 @@ func (c *phoenixChannel) handleInbound(msg phoenixMessage) {
 
 		// Message send reply (ref tracking)
 		if val, ok := c.pendingRefs.LoadAndDelete(msg.Ref); ok {
-			replyCh := val.(chan serverReply)
+			replyCh := val.(chan pendingReply)
 			var parsed struct {
 				Status   string `json:"status"`
 				Response struct {
@@ -466,7 +656,7 @@ func (c *phoenixChannel) handleInbound(msg phoenixMessage) {
 			}
 			json.Unmarshal(msg.Payload, &parsed)
 			select {
-			case replyCh <- serverReply{Status: parsed.Status, Reason: parsed.Response.Reason}:
+			case replyCh <- pendingReply{reply: ServerReply{Status: parsed.Status, Reason: parsed.Response.Reason}}:
 			default:
 			}
 		}
@@ -475,6 +665,12 @@ func (c *phoenixChannel) handleInbound(msg phoenixMessage) {
 			c.msgHandler(msg.Payload)
 		}
 	case "phx_error", "phx_close":
+		c.mu.Lock()
+		stale := msg.JoinRef != "" && msg.JoinRef != c.joinRef
+		c.mu.Unlock()
+		if stale {
+			return // the join replaced by UpdateProtocols shutting down
+		}
 		err := fmt.Errorf("channel %s", msg.Event)
 		c.rejectPendingRefs()
 		if c.disconnectFn != nil {
@@ -484,8 +680,12 @@ func (c *phoenixChannel) handleInbound(msg phoenixMessage) {
 	}
 }
 
-func (c *phoenixChannel) heartbeatLoop() {
-	ticker := time.NewTicker(30 * time.Second)
+// heartbeatLoop sends a Phoenix heartbeat every interval on the given connection
+// and force-closes it after MaxMissed consecutive heartbeats go unanswered, so a
+// half-open connection is detected and the reconnect path runs. It exits when
+// the connection is replaced or the channel is closed.
+func (c *phoenixChannel) heartbeatLoop(conn *websocket.Conn) {
+	ticker := time.NewTicker(c.heartbeat.Interval)
 	defer ticker.Stop()
 
 	for {
@@ -493,8 +693,37 @@ func (c *phoenixChannel) heartbeatLoop() {
 		case <-c.done:
 			return
 		case <-ticker.C:
+			c.mu.Lock()
+			if c.conn != conn {
+				c.mu.Unlock()
+				return
+			}
+			if c.hbRef != "" {
+				c.hbMissed++
+			}
+			missed := c.hbMissed
+			c.mu.Unlock()
+
+			if missed >= c.heartbeat.MaxMissed {
+				err := fmt.Errorf("connection dead: %d heartbeats missed", missed)
+				slog.Warn("heartbeat timeout", "missed", missed, "url", c.NodeURL())
+				c.mu.Lock()
+				if c.conn == conn {
+					c.dropErr = err
+				}
+				c.mu.Unlock()
+				conn.Close() // readLoop sees the error and starts reconnecting
+				return
+			}
+
+			ref := c.nextRef()
+			c.mu.Lock()
+			c.hbRef = ref
+			c.hbSentAt = time.Now()
+			c.mu.Unlock()
+
 			msg := phoenixMessage{
-				Ref:     c.nextRef(),
+				Ref:     ref,
 				Topic:   "phoenix",
 				Event:   "heartbeat",
 				Payload: json.RawMessage(`{}`),
@@ -506,6 +735,23 @@ func (c *phoenixChannel) heartbeatLoop() {
 	}
 }
 
+// handleHeartbeatReply clears the outstanding heartbeat and reports its round-trip time.
+func (c *phoenixChannel) handleHeartbeatReply(ref string) {
+	c.mu.Lock()
+	if ref == "" || ref != c.hbRef {
+		c.mu.Unlock()
+		return // late reply to a heartbeat already counted as missed
+	}
+	rtt := time.Since(c.hbSentAt)
+	c.hbRef = ""
+	c.hbMissed = 0
+	c.mu.Unlock()
+
+	if c.heartbeat.OnLatency != nil {
+		c.heartbeat.OnLatency(rtt)
+	}
+}
+
 func (c *phoenixChannel) nextRef() string {
 	c.mu.Lock()
 	defer c.mu.Unlock()
@@ -3,6 +3,7 @@ package layr8
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -99,11 +100,11 @@ func TestPhoenixChannel_Connect_JoinReply(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	err := ch.connect(ctx, []string{"https://layr8.io/protocols/echo/1.0"})
+	err := ch.Connect(ctx, []string{"https://layr8.io/protocols/echo/1.0"})
 	if err != nil {
-		t.Fatalf("connect() error: %v", err)
+		t.Fatalf("Connect() error: %v", err)
 	}
-	defer ch.close()
+	defer ch.Close()
 
 	// Verify join was sent with payload_types
 	received := mock.getReceived()
@@ -160,13 +161,13 @@ func TestPhoenixChannel_Send(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	ch.connect(ctx, []string{})
-	defer ch.close()
+	ch.Connect(ctx, []string{})
+	defer ch.Close()
 
 	payload := []byte(`{"id":"msg-1","type":"test","from":"did:web:test","to":["did:web:bob"],"body":{}}`)
-	_, err := ch.send(ctx, "message", payload)
+	_, err := ch.Send(ctx, "message", payload)
 	if err != nil {
-		t.Fatalf("send() error: %v", err)
+		t.Fatalf("Send() error: %v", err)
 	}
 
 	// Wait for server to receive
@@ -208,7 +209,7 @@ func TestPhoenixChannel_ReceiveMessage(t *testing.T) {
 
 	var receivedPayload []byte
 	done := make(chan struct{})
-	ch.setMessageHandler(func(payload []byte) {
+	ch.SetMessageHandler(func(payload []byte) {
 		receivedPayload = payload
 		close(done)
 	})
@@ -216,8 +217,8 @@ func TestPhoenixChannel_ReceiveMessage(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	ch.connect(ctx, []string{})
-	defer ch.close()
+	ch.Connect(ctx, []string{})
+	defer ch.Close()
 
 	// Server sends a message to client (topic must match channel's topic)
 	mock.sendToClient(phoenixMessage{
@@ -260,12 +261,12 @@ func TestPhoenixChannel_SendAck(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	ch.connect(ctx, []string{})
-	defer ch.close()
+	ch.Connect(ctx, []string{})
+	defer ch.Close()
 
-	err := ch.sendAck([]string{"msg-1", "msg-2"})
+	err := ch.SendAck([]string{"msg-1", "msg-2"})
 	if err != nil {
-		t.Fatalf("sendAck() error: %v", err)
+		t.Fatalf("SendAck() error: %v", err)
 	}
 
 	time.Sleep(100 * time.Millisecond)
@@ -316,9 +317,9 @@ func TestPhoenixChannel_JoinRejected_IncludesReason(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	err := ch.connect(ctx, []string{"https://layr8.io/protocols/echo/1.0"})
+	err := ch.Connect(ctx, []string{"https://layr8.io/protocols/echo/1.0"})
 	if err == nil {
-		ch.close()
+		ch.Close()
 		t.Fatal("expected error from rejected join")
 	}
 
@@ -355,11 +356,11 @@ func TestPhoenixChannel_AssignedDID(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	ch.connect(ctx, []string{})
-	defer ch.close()
+	ch.Connect(ctx, []string{})
+	defer ch.Close()
 
-	if ch.assignedDID() != "did:web:node:assigned-123" {
-		t.Errorf("assignedDID() = %q, want %q", ch.assignedDID(), "did:web:node:assigned-123")
+	if ch.AssignedDID() != "did:web:node:assigned-123" {
+		t.Errorf("AssignedDID() = %q, want %q", ch.AssignedDID(), "did:web:node:assigned-123")
 	}
 }
 
@@ -396,13 +397,13 @@ func TestPhoenixChannel_Send_ServerReply(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	ch.connect(ctx, []string{})
-	defer ch.close()
+	ch.Connect(ctx, []string{})
+	defer ch.Close()
 
 	payload := []byte(`{"id":"msg-1","type":"test","from":"did:web:test","to":["did:web:bob"],"body":{}}`)
-	reply, err := ch.send(ctx, "message", payload)
+	reply, err := ch.Send(ctx, "message", payload)
 	if err != nil {
-		t.Fatalf("send() error: %v", err)
+		t.Fatalf("Send() error: %v", err)
 	}
 	if reply.Status != "ok" {
 		t.Errorf("reply.Status = %q, want %q", reply.Status, "ok")
@@ -442,13 +443,13 @@ func TestPhoenixChannel_Send_ServerReject(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	ch.connect(ctx, []string{})
-	defer ch.close()
+	ch.Connect(ctx, []string{})
+	defer ch.Close()
 
 	payload := []byte(`{"id":"msg-1","type":"test","from":"did:web:test","to":["did:web:bob"],"body":{}}`)
-	reply, err := ch.send(ctx, "message", payload)
+	reply, err := ch.Send(ctx, "message", payload)
 	if err != nil {
-		t.Fatalf("send() error: %v", err)
+		t.Fatalf("Send() error: %v", err)
 	}
 	if reply.Status != "error" {
 		t.Errorf("reply.Status = %q, want %q", reply.Status, "error")
@@ -481,16 +482,16 @@ func TestPhoenixChannel_Send_Timeout(t *testing.T) {
 
 	connectCtx, connectCancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer connectCancel()
-	ch.connect(connectCtx, []string{})
-	defer ch.close()
+	ch.Connect(connectCtx, []string{})
+	defer ch.Close()
 
 	shortCtx, shortCancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
 	defer shortCancel()
 
 	payload := []byte(`{"id":"msg-1","type":"test","body":{}}`)
-	_, err := ch.send(shortCtx, "message", payload)
+	_, err := ch.Send(shortCtx, "message", payload)
 	if err == nil {
-		t.Fatal("send() should error on timeout")
+		t.Fatal("Send() should error on timeout")
 	}
 }
 
@@ -523,13 +524,13 @@ func TestPhoenixChannel_ReconnectAfterDrop(t *testing.T) {
 	disconnected := make(chan struct{}, 1)
 	reconnected := make(chan struct{}, 1)
 
-	ch.onDisconnect(func(err error) {
+	ch.OnDisconnect(func(err error) {
 		select {
 		case disconnected <- struct{}{}:
 		default:
 		}
 	})
-	ch.onReconnect(func() {
+	ch.OnReconnect(func() {
 		select {
 		case reconnected <- struct{}{}:
 		default:
@@ -539,10 +540,10 @@ func TestPhoenixChannel_ReconnectAfterDrop(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	if err := ch.connect(ctx, []string{"https://layr8.io/protocols/echo/1.0"}); err != nil {
-		t.Fatalf("connect() error: %v", err)
+	if err := ch.Connect(ctx, []string{"https://layr8.io/protocols/echo/1.0"}); err != nil {
+		t.Fatalf("Connect() error: %v", err)
 	}
-	defer ch.close()
+	defer ch.Close()
 
 	// Force-close the server-side WebSocket to simulate a drop
 	mock.mu.Lock()
@@ -593,14 +594,14 @@ func TestPhoenixChannel_FailFastDuringReconnect(t *testing.T) {
 	ch := newPhoenixChannel(wsURL, "test-key", "did:web:test")
 
 	disconnected := make(chan struct{})
-	ch.onDisconnect(func(err error) {
+	ch.OnDisconnect(func(err error) {
 		close(disconnected)
 	})
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	ch.connect(ctx, []string{})
+	ch.Connect(ctx, []string{})
 
 	// Shut down server so reconnect can't succeed, then force-close connection
 	server.Close()
@@ -620,13 +621,13 @@ func TestPhoenixChannel_FailFastDuringReconnect(t *testing.T) {
 	// Small delay to let reconnectLoop set reconnecting=true
 	time.Sleep(100 * time.Millisecond)
 
-	// send() should fail fast with ErrNotConnected
-	err := ch.sendFireAndForget("message", []byte(`{}`))
+	// Send() should fail fast with ErrNotConnected
+	err := ch.SendFireAndForget("message", []byte(`{}`))
 	if err != ErrNotConnected {
-		t.Errorf("sendFireAndForget during reconnect = %v, want ErrNotConnected", err)
+		t.Errorf("SendFireAndForget during reconnect = %v, want ErrNotConnected", err)
 	}
 
-	ch.close()
+	ch.Close()
 }
 
 func TestPhoenixChannel_CloseStopsReconnect(t *testing.T) {
@@ -650,18 +651,18 @@ func TestPhoenixChannel_CloseStopsReconnect(t *testing.T) {
 	ch := newPhoenixChannel(wsURL, "test-key", "did:web:test")
 
 	disconnected := make(chan struct{})
-	ch.onDisconnect(func(err error) {
+	ch.OnDisconnect(func(err error) {
 		close(disconnected)
 	})
 	reconnectCalled := false
-	ch.onReconnect(func() {
+	ch.OnReconnect(func() {
 		reconnectCalled = true
 	})
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	ch.connect(ctx, []string{})
+	ch.Connect(ctx, []string{})
 
 	// Shut down server so reconnect can't succeed
 	server.Close()
@@ -679,11 +680,11 @@ func TestPhoenixChannel_CloseStopsReconnect(t *testing.T) {
 	}
 
 	// Close should stop the reconnect loop
-	ch.close()
+	ch.Close()
 	time.Sleep(500 * time.Millisecond)
 
 	if reconnectCalled {
-		t.Error("onReconnect should not have been called after close()")
+		t.Error("OnReconnect should not have been called after Close()")
 	}
 }
 
@@ -710,12 +711,172 @@ func TestPhoenixChannel_SendFireAndForget(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	ch.connect(ctx, []string{})
-	defer ch.close()
+	ch.Connect(ctx, []string{})
+	defer ch.Close()
 
 	payload := []byte(`{"id":"msg-1","type":"test","body":{}}`)
-	err := ch.sendFireAndForget("message", payload)
+	err := ch.SendFireAndForget("message", payload)
 	if err != nil {
-		t.Fatalf("sendFireAndForget() error: %v", err)
+		t.Fatalf("SendFireAndForget() error: %v", err)
+	}
+}
+
+func TestPhoenixChannel_ReconnectGivesUp(t *testing.T) {
+	mock := newMockServer()
+	mock.onMsg = func(msg phoenixMessage) {
+		if msg.Event == "phx_join" {
+			mock.sendToClient(phoenixMessage{
+				JoinRef: msg.Ref,
+				Ref:     msg.Ref,
+				Topic:   msg.Topic,
+				Event:   "phx_reply",
+				Payload: json.RawMessage(`{"status":"ok","response":{}}`),
+			})
+		}
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(mock.handler))
+	defer server.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/plugin_socket/websocket"
+	gaveUp := make(chan error, 1)
+	ch := NewPhoenixTransport(Config{
+		NodeURL:  wsURL,
+		APIKey:   "test-key",
+		AgentDID: "did:web:test",
+		ReconnectPolicy: ReconnectPolicy{
+			InitialDelay: 10 * time.Millisecond,
+			MaxDelay:     20 * time.Millisecond,
+			MaxAttempts:  2,
+			OnGiveUp:     func(err error) { gaveUp <- err },
+		},
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := ch.Connect(ctx, []string{}); err != nil {
+		t.Fatalf("Connect() error: %v", err)
+	}
+	defer ch.Close()
+
+	// Shut down server so every reconnect attempt fails
+	server.Close()
+	mock.mu.Lock()
+	if mock.conn != nil {
+		mock.conn.Close()
+	}
+	mock.mu.Unlock()
+
+	select {
+	case err := <-gaveUp:
+		if !errors.Is(err, ErrReconnectGaveUp) {
+			t.Errorf("OnGiveUp error = %v, want ErrReconnectGaveUp", err)
+		}
+		if !strings.Contains(err.Error(), "2 attempts") {
+			t.Errorf("OnGiveUp error = %q, want attempt count", err)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("timeout waiting for OnGiveUp")
+	}
+
+	if err := ch.SendFireAndForget("message", []byte(`{}`)); err != ErrNotConnected {
+		t.Errorf("SendFireAndForget after give-up = %v, want ErrNotConnected", err)
+	}
+}
+
+func TestPhoenixChannel_BackoffContinuesWhenUnstable(t *testing.T) {
+	ch := newPhoenixChannel("ws://127.0.0.1:1/plugin_socket/websocket", "test-key", "did:web:test")
+	ch.reconnectPolicy = ReconnectPolicy{InitialDelay: time.Hour, MaxDelay: 100 * time.Hour, StableAfter: time.Hour}.withDefaults()
+	close(ch.done) // stop the loop right after it takes its first delay
+
+	// Previous outage left a grown backoff; connection was up only briefly.
+	bo := ch.reconnectPolicy.newBackoff()
+	bo.next()
+	ch.bo = bo
+	ch.attempts = 3
+	ch.connectedAt = time.Now()
+
+	ch.reconnectLoop(errors.New("drop"))
+	if ch.attempts != 3 {
+		t.Errorf("attempts = %d, want 3 (outage continues when connection was unstable)", ch.attempts)
+	}
+	if ch.bo != bo || bo.current != 4*time.Hour {
+		t.Errorf("backoff current = %v, want 4h (backoff continues when connection was unstable)", ch.bo.current)
+	}
+
+	// A connection that stayed up past StableAfter starts a fresh outage.
+	ch.reconnecting = false
+	ch.connectedAt = time.Now().Add(-2 * time.Hour)
+	ch.reconnectLoop(errors.New("drop"))
+	if ch.attempts != 0 {
+		t.Errorf("attempts = %d, want 0 after stable connection", ch.attempts)
+	}
+	if ch.bo != bo || bo.current != 2*time.Hour {
+		t.Errorf("backoff current = %v, want 2h (reset to InitialDelay, then one attempt)", ch.bo.current)
+	}
+}
+
+func TestPhoenixChannel_APIKeyPlacement(t *testing.T) {
+	tests := []struct {
+		name      string
+		placement APIKeyPlacement
+		query     string
+		header    string
+		bearer    string
+		joinKey   string
+	}{
+		{"query", APIKeyInQuery, "test-key", "", "", ""},
+		{"header", APIKeyInHeader, "", "test-key", "", ""},
+		{"bearer", APIKeyInBearer, "", "", "Bearer test-key", ""},
+		{"join payload", APIKeyInJoinPayload, "", "", "", "test-key"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := newMockServer()
+			mock.onMsg = func(msg phoenixMessage) {
+				if msg.Event == "phx_join" {
+					mock.sendToClient(phoenixMessage{
+						JoinRef: msg.Ref, Ref: msg.Ref, Topic: msg.Topic, Event: "phx_reply",
+						Payload: json.RawMessage(`{"status":"ok","response":{}}`),
+					})
+				}
+			}
+
+			var handshake *http.Request
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				handshake = r
+				mock.handler(w, r)
+			}))
+			defer server.Close()
+
+			wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/plugin_socket/websocket"
+			ch := newPhoenixChannel(wsURL, "test-key", "did:web:test")
+			ch.apiKeyPlacement = tt.placement
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := ch.Connect(ctx, []string{"https://didcomm.org/basicmessage/2.0"}); err != nil {
+				t.Fatalf("Connect() error: %v", err)
+			}
+			defer ch.Close()
+
+			if got := handshake.URL.Query().Get("api_key"); got != tt.query {
+				t.Errorf("api_key query = %q, want %q", got, tt.query)
+			}
+			if got := handshake.Header.Get("x-api-key"); got != tt.header {
+				t.Errorf("x-api-key header = %q, want %q", got, tt.header)
+			}
+			if got := handshake.Header.Get("Authorization"); got != tt.bearer {
+				t.Errorf("Authorization header = %q, want %q", got, tt.bearer)
+			}
+
+			var join struct {
+				APIKey string `json:"api_key"`
+			}
+			json.Unmarshal(mock.getReceived()[0].Payload, &join)
+			if join.APIKey != tt.joinKey {
+				t.Errorf("join api_key = %q, want %q", join.APIKey, tt.joinKey)
+			}
+		})
 	}
 }
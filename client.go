@@ -9,19 +9,39 @@ import (
 	"time"
 )
 
+// protocolUpdateTimeout bounds the rejoin triggered by a handler change on a
+// connected client.
+const protocolUpdateTimeout = 10 * time.Second
+
 // Client is the main entry point for interacting with the Layr8 platform.
 type Client struct {
 	cfg       Config
-	transport transport
+	transport Transport
 	rest      *restClient
 	registry  *handlerRegistry
 
-	connected bool
-	closed    bool
-	mu        sync.Mutex
+	state connState
+	mu    sync.Mutex // protects transport
 
 	agentDID string // resolved DID (explicit or assigned by node)
 	onError  ErrorHandler
+	outbox   *outbox  // nil unless Config.OutboundQueue is set
+	inflight inflight // running handlers and Requests awaiting a response, drained by Shutdown
+
+	dispatcher *dispatcher       // concurrency limits and backpressure for handlers
+	deliveries *deliveryAttempts // failed manual-ack runs per message ID
+	acks       *ackBatcher       // coalesces acks per Config.AckBatching
+	seen       SeenStore         // processed message IDs; nil unless Config.Deduplication is set
+	claims     *claimedIDs       // IDs being handled; nil unless Config.Deduplication is set
+
+	handlersMu sync.Mutex // serializes handler changes and the protocol updates they trigger
+
+	middlewareMu sync.RWMutex
+	middleware   []Middleware // added by Use, wraps every handler
+
+	// ctx is the parent of every handler context and is cancelled by Close.
+	ctx    context.Context
+	cancel context.CancelFunc
 
 	// Correlation map for Request/Response pattern
 	pending sync.Map // threadID -> chan *Message
@@ -45,109 +65,323 @@ func NewClient(cfg Config, onError ErrorHandler) (*Client, error) {
 	}
 
 	restURL := restURLFromWebSocket(resolved.NodeURL)
+	netCfg, _ := newNetConfig(resolved) // ProxyURL validated by resolveConfig
 
-	return &Client{
+	c := &Client{
 		cfg:      resolved,
-		rest:     newRestClient(restURL, resolved.APIKey),
+		rest:     newRestClient(restURL, resolved.Credentials, netCfg),
 		registry: newHandlerRegistry(),
 		agentDID: resolved.AgentDID,
 		onError:  onError,
-	}, nil
+
+		dispatcher: newDispatcher(resolved.MaxConcurrentHandlers, resolved.MaxQueuedMessages),
+		deliveries: newDeliveryAttempts(),
+	}
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+	c.acks = newAckBatcher(resolved.AckBatching, func(ids []string) {
+		if t := c.currentTransport(); t != nil {
+			t.SendAck(ids)
+		}
+	})
+	if resolved.OutboundQueue != nil {
+		c.outbox = newOutbox(*resolved.OutboundQueue)
+	}
+	if resolved.Deduplication != nil {
+		c.seen = newSeenStore(*resolved.Deduplication)
+		c.claims = newClaimedIDs()
+	}
+	return c, nil
 }
 
-// Handle registers a handler for the given DIDComm message type.
-// Handlers must be registered before Connect(). The protocol base URI
-// is automatically derived and registered with the cloud-node on Connect().
+// Handle registers a handler for the given DIDComm message type. The
+// protocol base URI is automatically derived and registered with the
+// cloud-node on Connect(). Handlers may also be added to a connected client;
+// if that adds a protocol, the channel is rejoined with the new set.
 func (c *Client) Handle(msgType string, fn HandlerFunc, opts ...HandlerOption) error {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+	return c.addHandler(scopeType, msgType, ignoreContext(fn), opts)
+}
 
-	if c.connected {
-		return ErrAlreadyConnected
+// HandleContext registers a context-aware handler for the given DIDComm
+// message type. It behaves like Handle, except the handler receives a
+// context that is cancelled when the client is closed (including when a
+// Shutdown deadline expires) or when the WithTimeout deadline passes.
+func (c *Client) HandleContext(msgType string, fn HandlerFuncCtx, opts ...HandlerOption) error {
+	return c.addHandler(scopeType, msgType, fn, opts)
+}
+
+// HandleProtocol registers a handler for every message type of a protocol,
+// such as "https://layr8.io/protocols/echo/1.0", or of every minor version
+// of a major version, such as "https://layr8.io/protocols/echo/1.x".
+// A handler for the exact message type takes precedence. The protocol is
+// registered with the cloud-node like those derived by Handle.
+//
+// The cloud-node routes by exact protocol, so a ".x" range is registered for
+// each of its minor versions: ".0" by default, or those given with
+// WithMinorVersions. A version belongs to one protocol handler; registering
+// "echo/1.3" fails while an "echo/1.x" range registers minor version 3.
+func (c *Client) HandleProtocol(protocol string, fn HandlerFunc, opts ...HandlerOption) error {
+	return c.HandleProtocolContext(protocol, ignoreContext(fn), opts...)
+}
+
+// HandleProtocolContext is HandleProtocol for a context-aware handler.
+func (c *Client) HandleProtocolContext(protocol string, fn HandlerFuncCtx, opts ...HandlerOption) error {
+	key, err := normalizeProtocol(protocol)
+	if err != nil {
+		return err
+	}
+	return c.addHandler(scopeProtocol, key, fn, opts)
+}
+
+// HandleDefault registers a catch-all handler for messages no other handler
+// matches. It registers no protocols with the cloud-node, so it only sees
+// messages of protocols joined through other handlers.
+func (c *Client) HandleDefault(fn HandlerFunc, opts ...HandlerOption) error {
+	return c.HandleDefaultContext(ignoreContext(fn), opts...)
+}
+
+// HandleDefaultContext is HandleDefault for a context-aware handler.
+func (c *Client) HandleDefaultContext(fn HandlerFuncCtx, opts ...HandlerOption) error {
+	return c.addHandler(scopeDefault, defaultHandlerKey, fn, opts)
+}
+
+// Unhandle removes the handler for the given message type. On a connected
+// client, a protocol left without handlers is unregistered from the
+// cloud-node by rejoining the channel. Invocations already running or queued
+// finish normally; later messages of that type go to the next matching
+// handler, or are reported as ErrNoHandler.
+func (c *Client) Unhandle(msgType string) error {
+	return c.removeHandler(scopeType, msgType, fmt.Errorf("no handler registered for message type %q", msgType))
+}
+
+// UnhandleProtocol removes a handler registered with HandleProtocol.
+func (c *Client) UnhandleProtocol(protocol string) error {
+	key, err := normalizeProtocol(protocol)
+	if err != nil {
+		return err
 	}
+	return c.removeHandler(scopeProtocol, key, fmt.Errorf("no handler registered for protocol %q", key))
+}
+
+// UnhandleDefault removes the handler registered with HandleDefault.
+func (c *Client) UnhandleDefault() error {
+	return c.removeHandler(scopeDefault, defaultHandlerKey, errors.New("no default handler registered"))
+}
+
+func (c *Client) addHandler(scope handlerScope, key string, fn HandlerFuncCtx, opts []HandlerOption) error {
+	return c.changeHandlers(
+		func() error { return c.registry.add(scope, key, fn, opts) },
+		func() { c.registry.unregister(scope, key) },
+	)
+}
 
-	return c.registry.register(msgType, fn, opts...)
+func (c *Client) removeHandler(scope handlerScope, key string, notFound error) error {
+	var removed handlerEntry
+	return c.changeHandlers(
+		func() error {
+			entry, ok := c.registry.unregister(scope, key)
+			if !ok {
+				return notFound
+			}
+			removed = entry
+			return nil
+		},
+		func() { c.registry.restore(removed) },
+	)
 }
 
-// Connect establishes the WebSocket connection and joins the Phoenix Channel
-// with the protocols derived from registered handlers.
+// changeHandlers applies a registry change and, on a connected client, sends
+// the resulting protocols to the cloud-node. If that fails, undo reverts the
+// registry change.
+func (c *Client) changeHandlers(change func() error, undo func()) error {
+	c.handlersMu.Lock()
+	defer c.handlersMu.Unlock()
+
+	before := c.registry.protocols()
+	if err := change(); err != nil {
+		return err
+	}
+	if !c.isConnected() {
+		return nil // picked up by the next Connect
+	}
+	after := c.registry.protocols()
+	if sameProtocols(before, after) {
+		return nil
+	}
+
+	t := c.currentTransport()
+	ctx, cancel := context.WithTimeout(c.ctx, protocolUpdateTimeout)
+	defer cancel()
+	if err := t.UpdateProtocols(ctx, after); err != nil {
+		undo()
+		return fmt.Errorf("update protocols: %w", err)
+	}
+	return nil
+}
+
+// Connect establishes the transport connection (by default a WebSocket joined
+// to a Phoenix Channel) with the protocols derived from registered handlers.
 func (c *Client) Connect(ctx context.Context) error {
-	c.mu.Lock()
-	if c.connected {
-		c.mu.Unlock()
+	if !c.setState(StateConnecting, nil, StateIdle) {
+		if c.State() == StateClosed {
+			return ErrClientClosed
+		}
 		return ErrAlreadyConnected
 	}
-	if c.closed {
-		c.mu.Unlock()
-		return ErrClientClosed
+
+	// A transport left behind by a reconnect policy that gave up is closed
+	// first and replaced only once the new one connects. Until then the
+	// closed transport stays in place, so handlers still running fail their
+	// sends with ErrNotConnected.
+	if old := c.currentTransport(); old != nil {
+		old.Close()
 	}
-	c.mu.Unlock()
 
+	// Hold handler changes until the join completes, so none is missed
+	// between computing the protocols and the client becoming connected.
+	c.handlersMu.Lock()
+	defer c.handlersMu.Unlock()
 	protocols := c.registry.protocols()
 
-	ch := newPhoenixChannel(c.cfg.NodeURL, c.cfg.APIKey, c.cfg.AgentDID)
+	newTransport := c.cfg.Transport
+	if newTransport == nil {
+		newTransport = NewPhoenixTransport
+	}
+	cfg := c.cfg
+	cfg.ReconnectPolicy.OnGiveUp = c.handleGiveUp
+	ch := newTransport(cfg)
 
 	// Wire up message handler
-	ch.setMessageHandler(c.handleInboundMessage)
+	ch.SetMessageHandler(c.handleInboundMessage)
 
 	// Wire up disconnect/reconnect callbacks
-	if c.disconnectFn != nil {
-		ch.onDisconnect(c.disconnectFn)
-	}
-	if c.reconnectFn != nil {
-		ch.onReconnect(c.reconnectFn)
-	}
+	ch.OnDisconnect(c.handleDisconnect)
+	ch.OnReconnect(c.handleReconnect)
 
-	if err := ch.connect(ctx, protocols); err != nil {
+	if err := ch.Connect(ctx, protocols); err != nil {
+		c.setState(StateIdle, err, StateConnecting)
 		return err
 	}
 
 	// If no DID was provided, use the one assigned by the node
-	if c.agentDID == "" && ch.assignedDID() != "" {
-		c.agentDID = ch.assignedDID()
+	if c.agentDID == "" && ch.AssignedDID() != "" {
+		c.agentDID = ch.AssignedDID()
 	}
 
 	c.mu.Lock()
 	c.transport = ch
-	c.connected = true
 	c.mu.Unlock()
+	c.followNode()
+	if !c.setState(StateJoined, nil, StateConnecting) && c.State() == StateClosed {
+		// Close raced with Connect before the transport was published.
+		ch.Close()
+		return ErrClientClosed
+	}
+
+	// Flush messages persisted by a previous run (e.g. a file-backed queue).
+	if c.outbox != nil && c.outbox.store.Len() > 0 {
+		go c.flushOutbox()
+	}
 
 	return nil
 }
 
-// Close gracefully shuts down the client connection.
+// Close gracefully shuts down the client connection. Acks held back by
+// Config.AckBatching are sent first.
 func (c *Client) Close() error {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	if c.closed {
-		return nil
+	if !c.setState(StateClosed, nil) {
+		return nil // already closed
 	}
-	c.closed = true
-	c.connected = false
+	c.cancel()
 
-	if c.transport != nil {
-		return c.transport.close()
+	if t := c.currentTransport(); t != nil {
+		c.acks.flush()
+		return t.Close()
 	}
 	return nil
 }
 
+// currentTransport returns the transport. It is nil only before the first
+// Connect; afterwards it may be closed, but is never reset to nil.
+func (c *Client) currentTransport() Transport {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.transport
+}
+
 // DID returns the agent's DID — either the one provided in Config
 // or the ephemeral DID assigned by the cloud-node on Connect.
 func (c *Client) DID() string {
 	return c.agentDID
 }
 
+// NodeURL returns the WebSocket URL of the cloud-node the client is connected
+// to. Before Connect it returns the first configured node.
+func (c *Client) NodeURL() string {
+	t := c.currentTransport()
+	if t == nil {
+		return c.cfg.NodeURL
+	}
+	return t.NodeURL()
+}
+
 // OnDisconnect registers a callback invoked when the connection drops.
 func (c *Client) OnDisconnect(fn func(error)) {
 	c.disconnectFn = fn
 }
 
 // OnReconnect registers a callback invoked when the connection is restored.
+// With several configured nodes, call NodeURL from the callback to see which
+// node the client reconnected to.
 func (c *Client) OnReconnect(fn func()) {
 	c.reconnectFn = fn
 }
 
+// followNode points the REST client at the node the transport is connected to,
+// so REST calls follow a failover.
+func (c *Client) followNode() {
+	if u := c.NodeURL(); u != "" {
+		c.rest.setBaseURL(restURLFromWebSocket(u))
+	}
+}
+
+// isConnected reports whether Connect succeeded and the client has not been
+// closed or given up reconnecting. While reconnecting, the transport itself
+// rejects writes with ErrNotConnected (or the outbound queue buffers them).
+func (c *Client) isConnected() bool {
+	s := c.State()
+	return s == StateJoined || s == StateReconnecting || s == StateDraining
+}
+
+// handleDisconnect runs when the transport loses the connection.
+func (c *Client) handleDisconnect(err error) {
+	c.setState(StateReconnecting, err, StateConnecting, StateJoined)
+	if c.disconnectFn != nil {
+		c.disconnectFn(err)
+	}
+}
+
+// handleGiveUp runs when the reconnect policy is exhausted. The client returns
+// to StateIdle so Connect can be called again.
+func (c *Client) handleGiveUp(err error) {
+	c.setState(StateIdle, err, StateReconnecting)
+	if c.cfg.ReconnectPolicy.OnGiveUp != nil {
+		c.cfg.ReconnectPolicy.OnGiveUp(err)
+	}
+}
+
+// handleReconnect runs when the transport restores the connection.
+// It flushes the outbound queue and then notifies the OnReconnect callback.
+func (c *Client) handleReconnect() {
+	c.followNode()
+	c.setState(StateJoined, nil, StateReconnecting)
+	if c.outbox != nil {
+		go c.flushOutbox()
+	}
+	if c.reconnectFn != nil {
+		c.reconnectFn()
+	}
+}
+
 // handleInboundMessage is called by the transport for each inbound "message" event.
 func (c *Client) handleInboundMessage(payload []byte) {
 	msg, err := parseDIDComm(payload)
@@ -158,8 +392,10 @@ func (c *Client) handleInboundMessage(payload []byte) {
 			Cause:     err,
 			Timestamp: time.Now(),
 		})
+		c.handleUnhandled(payload, salvageMessage(payload), ErrParseFailure, err)
 		return
 	}
+	msg.raw = payload
 
 	// Check if this is a response to a pending Request.
 	// Match on thid first (normal responses), then fall back to pthid
@@ -185,10 +421,16 @@ func (c *Client) handleInboundMessage(payload []byte) {
 		}
 	}
 
+	// While draining, leave new messages unacknowledged so the cloud-node
+	// redelivers them after the agent restarts.
+	if c.State() == StateDraining {
+		return
+	}
+
 	// Problem reports that don't match a pending Request are orphaned
 	// (the original request already timed out). Ack and report, don't ErrNoHandler.
 	if isProblemReport(msg.Type) {
-		c.transport.sendAck([]string{msg.ID})
+		c.ack(msg.ID)
 		var prob ProblemReportError
 		if err := msg.UnmarshalBody(&prob); err == nil {
 			c.onError(SDKError{
@@ -213,21 +455,17 @@ func (c *Client) handleInboundMessage(payload []byte) {
 			From:      msg.From,
 			Timestamp: time.Now(),
 		})
+		c.handleUnhandled(payload, msg, ErrNoHandler, nil)
 		return
 	}
 
-	// Auto-ack before handler (unless manual ack)
-	if !entry.manualAck {
-		c.transport.sendAck([]string{msg.ID})
-	} else {
-		// Set up manual ack function
-		msg.ackFn = func(id string) {
-			c.transport.sendAck([]string{id})
-		}
+	if c.skipDuplicate(msg) {
+		return
 	}
 
-	// Run handler asynchronously
-	go c.runHandler(entry, msg)
+	// Run handler asynchronously, subject to concurrency limits.
+	// It is acknowledged when it starts (unless manual ack).
+	c.dispatch(entry, msg)
 }
 
 func (c *Client) runHandler(entry handlerEntry, msg *Message) {
@@ -243,36 +481,104 @@ func (c *Client) runHandler(entry handlerEntry, msg *Message) {
 				Cause:     err,
 				Timestamp: time.Now(),
 			})
+			switch msg.AckState() {
+			case AckStatePending:
+				c.handlerFailed(msg, err)
+			case AckStateAcked, AckStateRejected:
+				c.markProcessed(msg, nil)
+			}
 		}
 	}()
 
-	resp, err := entry.fn(msg)
+	ctx := context.WithValue(c.ctx, messageContextKey{}, msg)
+	if entry.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, entry.timeout)
+		defer cancel()
+	}
+
+	resp, err := c.chain(entry)(ctx, msg)
+
+	if c.ctx.Err() != nil {
+		return // client closed while the handler ran; nowhere to reply
+	}
+
+	state := msg.AckState()
+	switch state {
+	case AckStateNacked:
+		return // settled by the handler, which also decided what the sender gets
+	case AckStateRejected:
+		c.markProcessed(msg, nil)
+		return
+	case AckStatePending: // manual ack only
+		if err != nil {
+			c.sendProblemReport(msg, err)
+			c.handlerFailed(msg, err)
+			return
+		}
+		c.onError(SDKError{
+			Kind:      ErrMissingAck,
+			MessageID: msg.ID,
+			Type:      msg.Type,
+			From:      msg.From,
+			Cause:     errors.New("handler returned without Ack, Nack or Reject; the message will be redelivered"),
+			Timestamp: time.Now(),
+		})
+	}
+	if entry.manualAck {
+		c.deliveries.forget(msg.ID)
+	}
 
 	if err != nil {
 		// Send problem report
 		c.sendProblemReport(msg, err)
+		if state == AckStateAcked {
+			c.markProcessed(msg, nil) // acked despite the error
+		}
 		return
 	}
 
 	if resp != nil {
-		// Auto-fill response fields
-		if resp.From == "" {
-			resp.From = c.agentDID
-		}
-		if len(resp.To) == 0 && msg.From != "" {
-			resp.To = []string{msg.From}
-		}
-		if resp.ThreadID == "" && msg.ThreadID != "" {
-			resp.ThreadID = msg.ThreadID
-		} else if resp.ThreadID == "" {
-			resp.ThreadID = msg.ID
-		}
+		c.sendMessage(c.fillResponse(msg, resp))
+	}
+	if state == AckStateAcked { // not for a missing ack: the redelivery must run
+		c.markProcessed(msg, resp)
+	}
+}
 
-		c.sendMessage(resp)
+// fillResponse addresses a handler's response to the sender of msg, on its
+// thread, unless the handler set those fields itself.
+func (c *Client) fillResponse(msg, resp *Message) *Message {
+	if resp.From == "" {
+		resp.From = c.agentDID
+	}
+	if len(resp.To) == 0 && msg.From != "" {
+		resp.To = []string{msg.From}
+	}
+	if resp.ThreadID == "" && msg.ThreadID != "" {
+		resp.ThreadID = msg.ThreadID
+	} else if resp.ThreadID == "" {
+		resp.ThreadID = msg.ID
 	}
+	return resp
 }
 
+// sendProblemReport replies to original with a problem report for a handler
+// error. A *ProblemReportError is sent as-is; any other error is reported as
+// "e.p.xfer.cant-process" with the error text as the comment.
 func (c *Client) sendProblemReport(original *Message, handlerErr error) {
+	var prob *ProblemReportError
+	if !errors.As(handlerErr, &prob) {
+		prob = &ProblemReportError{
+			Code:    "e.p.xfer.cant-process",
+			Comment: handlerErr.Error(),
+		}
+	}
+	c.replyProblem(original, prob)
+}
+
+// replyProblem sends a problem report on the thread of the original message.
+func (c *Client) replyProblem(original *Message, prob *ProblemReportError) {
 	threadID := original.ThreadID
 	if threadID == "" {
 		threadID = original.ID
@@ -281,10 +587,7 @@ func (c *Client) sendProblemReport(original *Message, handlerErr error) {
 		Type:     "https://didcomm.org/report-problem/2.0/problem-report",
 		To:       []string{original.From},
 		ThreadID: threadID,
-		Body: &ProblemReportError{
-			Code:    "e.p.xfer.cant-process",
-			Comment: handlerErr.Error(),
-		},
+		Body:     prob,
 	}
 	c.sendMessage(report)
 }
@@ -293,12 +596,9 @@ func (c *Client) sendProblemReport(original *Message, handlerErr error) {
 // By default it blocks until the server replies (poka-yoke: callers see rejections).
 // Use WithFireAndForget() to skip waiting for the server reply.
 func (c *Client) Send(ctx context.Context, msg *Message, opts ...SendOption) error {
-	c.mu.Lock()
-	if !c.connected {
-		c.mu.Unlock()
+	if !c.isConnected() {
 		return ErrNotConnected
 	}
-	c.mu.Unlock()
 
 	o := sendDefaults()
 	for _, opt := range opts {
@@ -318,27 +618,20 @@ func (c *Client) Send(ctx context.Context, msg *Message, opts ...SendOption) err
 	}
 
 	if o.fireAndForget {
-		return c.transport.sendFireAndForget("message", data)
+		return c.writeMessage(msg.ID, data)
 	}
-
-	reply, err := c.transport.send(ctx, "message", data)
-	if err != nil {
-		return err
-	}
-	if reply.Status == "error" {
-		return fmt.Errorf("server rejected message: %s", reply.Reason)
-	}
-	return nil
+	return c.deliverMessage(ctx, msg.ID, data)
 }
 
 // Request sends a message and blocks until a correlated response arrives or the context expires.
+// With WithRetry, a request that may have been lost is resent and errors are
+// returned as a *RequestError.
 func (c *Client) Request(ctx context.Context, msg *Message, opts ...RequestOption) (*Message, error) {
-	c.mu.Lock()
-	if !c.connected {
-		c.mu.Unlock()
+	if !c.isConnected() {
 		return nil, ErrNotConnected
 	}
-	c.mu.Unlock()
+	c.inflight.add()
+	defer c.inflight.done()
 
 	o := requestDefaults()
 	for _, opt := range opts {
@@ -368,31 +661,125 @@ func (c *Client) Request(ctx context.Context, msg *Message, opts ...RequestOptio
 	if err != nil {
 		return nil, err
 	}
-	reply, err := c.transport.send(ctx, "message", data)
-	if err != nil {
-		return nil, err
+	if o.retries > 0 {
+		return c.requestWithRetry(ctx, msg.ID, data, respCh, o)
 	}
-	if reply.Status == "error" {
-		return nil, fmt.Errorf("server rejected message: %s", reply.Reason)
+	if err := c.deliverMessage(ctx, msg.ID, data); err != nil {
+		return nil, err
 	}
 
 	// Wait for DIDComm response or timeout
 	select {
 	case resp := <-respCh:
-		// Check if response is a problem report
-		if resp.Type == "https://didcomm.org/report-problem/2.0/problem-report" {
-			var prob ProblemReportError
-			if err := resp.UnmarshalBody(&prob); err != nil {
-				return nil, fmt.Errorf("failed to parse problem report: %w", err)
-			}
-			return nil, &prob
-		}
-		return resp, nil
+		return requestResult(resp)
 	case <-ctx.Done():
 		return nil, ctx.Err()
 	}
 }
 
+// requestResult turns a response into Request's result: a problem report
+// becomes a *ProblemReportError.
+func requestResult(resp *Message) (*Message, error) {
+	if resp.Type == "https://didcomm.org/report-problem/2.0/problem-report" {
+		var prob ProblemReportError
+		if err := resp.UnmarshalBody(&prob); err != nil {
+			return nil, fmt.Errorf("failed to parse problem report: %w", err)
+		}
+		return nil, &prob
+	}
+	return resp, nil
+}
+
+// errNoResponse is the attempt error when a retried request got no response
+// within its backoff.
+var errNoResponse = errors.New("no response")
+
+// requestWithRetry sends data and waits for the response, resending it as
+// configured by WithRetry.
+func (c *Client) requestWithRetry(ctx context.Context, id string, data []byte, respCh chan *Message, o requestOptions) (*Message, error) {
+	watchCtx, stopWatch := context.WithCancel(ctx)
+	defer stopWatch()
+	changes := c.WatchState(watchCtx)
+
+	wait := o.backoff
+	for attempt := 1; ; attempt++ {
+		last := attempt > o.retries
+		resp, retry, err := c.requestAttempt(ctx, id, data, respCh, changes, wait, last)
+		if !retry {
+			if err != nil {
+				return nil, &RequestError{MessageID: id, Attempts: attempt, Err: err}
+			}
+			return resp, nil
+		}
+		wait *= 2
+	}
+}
+
+// requestAttempt sends data once and waits for the response. retry reports
+// whether the request may have been lost and should be sent again: the send
+// failed on a dropped connection, the connection was restored, or no
+// response came within wait. The last attempt is never retried.
+func (c *Client) requestAttempt(ctx context.Context, id string, data []byte, respCh chan *Message, changes <-chan StateChange, wait time.Duration, last bool) (resp *Message, retry bool, err error) {
+	var timeout <-chan time.Time
+	if wait > 0 && !last {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	if last {
+		changes = nil
+	}
+
+	if err := c.deliverMessage(ctx, id, data); err != nil {
+		if last || ctx.Err() != nil || !connectionLost(err) {
+			return nil, false, err
+		}
+		// The connection dropped: resend once it is back or the backoff has passed.
+		for {
+			select {
+			case change, ok := <-changes:
+				if !ok {
+					return nil, false, err // client closed
+				}
+				if change.To != StateJoined {
+					continue
+				}
+			case <-timeout:
+			case <-ctx.Done():
+				return nil, false, ctx.Err()
+			}
+			return nil, true, err
+		}
+	}
+
+	for {
+		select {
+		case resp := <-respCh:
+			resp, err := requestResult(resp)
+			return resp, false, err
+		case change, ok := <-changes:
+			if !ok {
+				changes = nil // client closed; wait for ctx
+				continue
+			}
+			if change.To == StateJoined {
+				return nil, true, errors.New("connection restored without a response")
+			}
+		case <-timeout:
+			return nil, true, errNoResponse
+		case <-ctx.Done():
+			return nil, false, ctx.Err()
+		}
+	}
+}
+
+// connectionLost reports whether err means the send failed because the
+// connection was down or dropped before the server replied, so the message
+// may not have reached the node and is safe to resend.
+func connectionLost(err error) bool {
+	return errors.Is(err, ErrNotConnected) || errors.Is(err, ErrConnectionLost)
+}
+
 // isProblemReport checks if a message type is a DIDComm problem report.
 func isProblemReport(msgType string) bool {
 	return strings.HasPrefix(msgType, "https://didcomm.org/report-problem/")
@@ -416,6 +803,7 @@ func (c *Client) sendMessage(msg *Message) error {
 	// messages are sent as bare DIDComm JSON.
 	// Uses fire-and-forget because this is called from handler goroutines
 	// (runHandler, sendProblemReport) where there's no caller context.
-	// Send() and Request() use transport.send() for proper reply handling.
-	return c.transport.sendFireAndForget("message", data)
+	// Send() and Request() use transport.Send() for proper reply handling.
+	// With an outbound queue, responses issued during a disconnect are queued.
+	return c.writeMessage(msg.ID, data)
 }
@@ -1,21 +1,20 @@
 package layr8
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"slices"
 	"strings"
 	"sync"
 	"testing"
 	"time"
 )
 
-// discardErrors is a no-op ErrorHandler used in tests that don't assert error handler behavior.
-var discardErrors = func(SDKError) {}
-
 func setupMockServer(t *testing.T) (*mockPhoenixServer, *httptest.Server, string) {
 	t.Helper()
 	mock := newMockServer()
@@ -100,13 +99,37 @@ func TestClient_Handle_BeforeConnect(t *testing.T) {
 }
 
 func TestClient_Handle_AfterConnect(t *testing.T) {
-	_, _, wsURL := setupMockServer(t)
+	mock, _, wsURL := setupMockServer(t)
+	joins := make(chan phoenixMessage, 4)
+	reply := mock.onMsg
+	mock.onMsg = func(msg phoenixMessage) {
+		if msg.Event == "phx_join" {
+			joins <- msg
+		}
+		reply(msg)
+	}
+	nextJoin := func() []string {
+		t.Helper()
+		select {
+		case msg := <-joins:
+			var params struct {
+				PayloadTypes []string `json:"payload_types"`
+			}
+			json.Unmarshal(msg.Payload, &params)
+			return params.PayloadTypes
+		case <-time.After(2 * time.Second):
+			t.Fatal("timeout waiting for phx_join")
+			return nil
+		}
+	}
 
 	client, _ := NewClient(Config{
 		NodeURL:  wsURL,
 		APIKey:   "test-key",
 		AgentDID: "did:web:test",
 	}, discardErrors)
+	disconnected := make(chan error, 1)
+	client.OnDisconnect(func(err error) { disconnected <- err })
 	client.Handle("https://layr8.io/protocols/echo/1.0/request",
 		func(msg *Message) (*Message, error) { return nil, nil },
 	)
@@ -114,14 +137,146 @@ func TestClient_Handle_AfterConnect(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	client.Connect(ctx)
+	if err := client.Connect(ctx); err != nil {
+		t.Fatalf("Connect() error: %v", err)
+	}
 	defer client.Close()
+	nextJoin()
+	mock.mu.Lock()
+	firstJoinRef := mock.received[0].Ref
+	mock.mu.Unlock()
+
+	handled := make(chan string, 1)
+	err := client.Handle("https://layr8.io/protocols/ping/1.0/ping",
+		func(msg *Message) (*Message, error) {
+			handled <- msg.ID
+			return nil, nil
+		},
+	)
+	if err != nil {
+		t.Fatalf("Handle() after Connect error: %v", err)
+	}
+	if got := nextJoin(); !slices.Contains(got, "https://layr8.io/protocols/ping/1.0") ||
+		!slices.Contains(got, "https://layr8.io/protocols/echo/1.0") {
+		t.Errorf("rejoin payload_types = %v, want echo and ping protocols", got)
+	}
+
+	// The node shuts down the replaced join; that is not a disconnect.
+	mock.sendToClient(phoenixMessage{
+		JoinRef: firstJoinRef,
+		Topic:   "plugins:did:web:test",
+		Event:   "phx_close",
+		Payload: json.RawMessage(`{}`),
+	})
+	mock.sendToClient(phoenixMessage{
+		Topic:   "plugins:did:web:test",
+		Event:   "message",
+		Payload: inboundPayload("ping-1", "https://layr8.io/protocols/ping/1.0/ping", ""),
+	})
+	select {
+	case id := <-handled:
+		if id != "ping-1" {
+			t.Errorf("handled %q, want ping-1", id)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("handler registered after Connect did not run")
+	}
+	select {
+	case err := <-disconnected:
+		t.Fatalf("OnDisconnect(%v) fired for a stale phx_close", err)
+	case <-time.After(50 * time.Millisecond):
+	}
 
-	err := client.Handle("https://layr8.io/protocols/echo/1.0/response",
+	// A new type within an already-joined protocol needs no rejoin.
+	client.Handle("https://layr8.io/protocols/ping/1.0/pong",
 		func(msg *Message) (*Message, error) { return nil, nil },
 	)
-	if err == nil {
-		t.Fatal("Handle() after Connect should return error")
+	select {
+	case <-joins:
+		t.Error("Handle() for a joined protocol should not rejoin")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// Removing the last handler of a protocol rejoins without it.
+	client.Unhandle("https://layr8.io/protocols/ping/1.0/ping")
+	client.Unhandle("https://layr8.io/protocols/ping/1.0/pong")
+	if got := nextJoin(); slices.Contains(got, "https://layr8.io/protocols/ping/1.0") {
+		t.Errorf("rejoin payload_types = %v, want ping protocol removed", got)
+	}
+}
+
+func TestClient_Handle_AfterConnect_UpdateFails(t *testing.T) {
+	fake := &fakeTransport{}
+	client := newTestClient(t, fake, Config{}, nil)
+	client.Handle(dispatchTypeA, func(msg *Message) (*Message, error) { return nil, nil })
+	connectTestClient(t, client)
+
+	fake.mu.Lock()
+	fake.updateErr = errors.New("join rejected")
+	fake.mu.Unlock()
+
+	const otherType = "https://layr8.io/protocols/other/1.0/request"
+	if err := client.Handle(otherType, func(msg *Message) (*Message, error) { return nil, nil }); err == nil {
+		t.Fatal("Handle() should fail when the protocol update fails")
+	}
+	if _, ok := client.registry.lookup(otherType); ok {
+		t.Error("failed Handle() should not leave the handler registered")
+	}
+	if err := client.Unhandle(dispatchTypeA); err == nil {
+		t.Fatal("Unhandle() should fail when the protocol update fails")
+	}
+	if _, ok := client.registry.lookup(dispatchTypeA); !ok {
+		t.Error("failed Unhandle() should restore the handler")
+	}
+	if err := client.Unhandle(otherType); err == nil {
+		t.Error("Unhandle() of an unregistered type should fail")
+	}
+}
+
+func TestClient_HandleProtocolAndDefault(t *testing.T) {
+	fake := &fakeTransport{}
+	client := newTestClient(t, fake, Config{}, nil)
+	handled := make(chan string, 2)
+	record := func(name string) HandlerFunc {
+		return func(msg *Message) (*Message, error) {
+			handled <- name + ":" + msg.ID
+			return nil, nil
+		}
+	}
+	if err := client.HandleProtocol("https://layr8.io/protocols/jobs/1.x/", record("jobs")); err != nil {
+		t.Fatalf("HandleProtocol() error: %v", err)
+	}
+	if err := client.HandleDefault(record("default")); err != nil {
+		t.Fatalf("HandleDefault() error: %v", err)
+	}
+	if err := client.HandleProtocol("https://layr8.io/protocols/jobs", record("bad")); err == nil {
+		t.Error("HandleProtocol() without a version should fail")
+	}
+	connectTestClient(t, client)
+
+	fake.mu.Lock()
+	joined := fake.protocols
+	fake.mu.Unlock()
+	if !slices.Contains(joined, "https://layr8.io/protocols/jobs/1.0") {
+		t.Errorf("joined protocols = %v, want jobs/1.0 for the 1.x handler", joined)
+	}
+
+	fake.handler(inboundPayload("m1", "https://layr8.io/protocols/jobs/1.4/start", ""))
+	fake.handler(inboundPayload("m2", "https://layr8.io/protocols/other/1.0/x", ""))
+	got := []string{<-handled, <-handled}
+	slices.Sort(got)
+	if want := []string{"default:m2", "jobs:m1"}; !slices.Equal(got, want) {
+		t.Errorf("handled %v, want %v", got, want)
+	}
+
+	if err := client.UnhandleDefault(); err != nil {
+		t.Errorf("UnhandleDefault() error: %v", err)
+	}
+	if err := client.UnhandleDefault(); err == nil {
+		t.Error("UnhandleDefault() without a default handler should fail")
+	}
+	if err := client.UnhandleProtocol("https://layr8.io/protocols/jobs/1.x"); err != nil {
+		t.Errorf("UnhandleProtocol() error: %v", err)
 	}
 }
 
@@ -1238,3 +1393,280 @@ func TestClient_OnError_HandlerPanic(t *testing.T) {
 		t.Error("handler panic should result in a problem report being sent")
 	}
 }
+
+// fakeTransport is a minimal in-process Transport used to verify the Config.Transport hook.
+type fakeTransport struct {
+	mu        sync.Mutex
+	protocols []string
+	sent      [][]byte
+	acks      []string
+	ackFrames int // SendAck calls
+	handler   func(payload []byte)
+	closed    bool
+	down      bool // simulates a dropped connection: sends fail with ErrNotConnected
+	reconnect func()
+	drop      func(error) // OnDisconnect callback
+	updates   [][]string  // protocols passed to UpdateProtocols
+	updateErr error       // returned by UpdateProtocols
+}
+
+func (f *fakeTransport) Connect(ctx context.Context, protocols []string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.protocols = protocols
+	return nil
+}
+
+func (f *fakeTransport) UpdateProtocols(ctx context.Context, protocols []string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.updateErr != nil {
+		return f.updateErr
+	}
+	f.updates = append(f.updates, protocols)
+	f.protocols = protocols
+	return nil
+}
+
+func (f *fakeTransport) Send(ctx context.Context, event string, payload []byte) (ServerReply, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.down {
+		return ServerReply{}, ErrNotConnected
+	}
+	f.sent = append(f.sent, payload)
+	return ServerReply{Status: "ok"}, nil
+}
+
+func (f *fakeTransport) SendFireAndForget(event string, payload []byte) error {
+	_, err := f.Send(context.Background(), event, payload)
+	return err
+}
+
+func (f *fakeTransport) SendAck(ids []string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.acks = append(f.acks, ids...)
+	f.ackFrames++
+	return nil
+}
+
+func (f *fakeTransport) SetMessageHandler(fn func(payload []byte)) { f.handler = fn }
+func (f *fakeTransport) OnDisconnect(fn func(error))               { f.drop = fn }
+func (f *fakeTransport) OnReconnect(fn func())                     { f.reconnect = fn }
+func (f *fakeTransport) AssignedDID() string                       { return "did:web:fake:assigned" }
+func (f *fakeTransport) NodeURL() string                           { return "ws://fake.invalid/plugin_socket/websocket" }
+
+func (f *fakeTransport) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.closed = true
+	return nil
+}
+
+func TestClient_CustomTransport(t *testing.T) {
+	fake := &fakeTransport{}
+	var gotCfg Config
+
+	client, err := NewClient(Config{
+		NodeURL: "ws://localhost:4000/plugin_socket/websocket",
+		APIKey:  "test-key",
+		Transport: func(cfg Config) Transport {
+			gotCfg = cfg
+			return fake
+		},
+	}, discardErrors)
+	if err != nil {
+		t.Fatalf("NewClient() error: %v", err)
+	}
+
+	handled := make(chan *Message, 1)
+	client.Handle("https://layr8.io/protocols/echo/1.0/request",
+		func(msg *Message) (*Message, error) {
+			handled <- msg
+			return nil, nil
+		},
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Connect(ctx); err != nil {
+		t.Fatalf("Connect() error: %v", err)
+	}
+
+	if gotCfg.APIKey != "test-key" {
+		t.Errorf("factory cfg.APIKey = %q, want %q", gotCfg.APIKey, "test-key")
+	}
+	if client.DID() != "did:web:fake:assigned" {
+		t.Errorf("DID() = %q, want DID assigned by transport", client.DID())
+	}
+	if len(fake.protocols) != 2 {
+		t.Errorf("protocols = %v, want echo/1.0 + report-problem", fake.protocols)
+	}
+
+	if err := client.Send(ctx, &Message{Type: "https://didcomm.org/basicmessage/2.0/message", To: []string{"did:web:bob"}}); err != nil {
+		t.Fatalf("Send() error: %v", err)
+	}
+
+	fake.handler([]byte(`{"plaintext":{"id":"in-1","type":"https://layr8.io/protocols/echo/1.0/request","from":"did:web:bob","body":{}}}`))
+	select {
+	case msg := <-handled:
+		if msg.ID != "in-1" {
+			t.Errorf("msg.ID = %q, want %q", msg.ID, "in-1")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for handler")
+	}
+
+	client.Close()
+
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	if len(fake.sent) != 1 {
+		t.Errorf("sent = %d messages, want 1", len(fake.sent))
+	}
+	if len(fake.acks) != 1 || fake.acks[0] != "in-1" {
+		t.Errorf("acks = %v, want [in-1]", fake.acks)
+	}
+	if !fake.closed {
+		t.Error("Close() should close the custom transport")
+	}
+}
+
+func TestClient_HandleContext_CancelledOnClose(t *testing.T) {
+	fake := &fakeTransport{}
+	client := newTestClient(t, fake, Config{}, nil)
+	got := make(chan *Message, 1)
+	done := make(chan error, 1)
+	client.HandleContext(dispatchTypeA, func(ctx context.Context, msg *Message) (*Message, error) {
+		m, _ := MessageFromContext(ctx)
+		got <- m
+		<-ctx.Done()
+		done <- ctx.Err()
+		return nil, ctx.Err()
+	})
+	connectTestClient(t, client)
+
+	fake.handler(inboundPayload("m1", dispatchTypeA, ""))
+	if m := <-got; m == nil || m.ID != "m1" {
+		t.Fatalf("MessageFromContext() = %v, want message m1", m)
+	}
+
+	client.Close()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("ctx.Err() = %v, want context.Canceled", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("handler context was not cancelled by Close")
+	}
+	time.Sleep(20 * time.Millisecond)
+	if n := len(fake.sentIDs()); n != 0 {
+		t.Errorf("sent %d messages after Close, want none", n)
+	}
+}
+
+func TestClient_HandleContext_WithTimeout(t *testing.T) {
+	fake := &fakeTransport{}
+	client := newTestClient(t, fake, Config{}, nil)
+	client.HandleContext(dispatchTypeA, func(ctx context.Context, msg *Message) (*Message, error) {
+		if _, ok := ctx.Deadline(); !ok {
+			t.Error("handler context should have a deadline")
+		}
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}, WithTimeout(20*time.Millisecond))
+	connectTestClient(t, client)
+
+	fake.handler(inboundPayload("m1", dispatchTypeA, ""))
+	waitForSent(t, fake, 1)
+
+	fake.mu.Lock()
+	var report struct {
+		Body ProblemReportError `json:"body"`
+	}
+	json.Unmarshal(fake.sent[0], &report)
+	fake.mu.Unlock()
+	if !strings.Contains(report.Body.Comment, context.DeadlineExceeded.Error()) {
+		t.Errorf("problem report comment = %q, want the deadline error", report.Body.Comment)
+	}
+}
+
+func TestClient_Request_WithRetry_ResendsAfterBackoff(t *testing.T) {
+	fake := &fakeTransport{}
+	client := newTestClient(t, fake, Config{}, nil)
+	connectTestClient(t, client)
+
+	go func() {
+		for len(fake.sentIDs()) < 2 { // the first attempt goes unanswered
+			time.Sleep(time.Millisecond)
+		}
+		answerRequest(fake, func(json.RawMessage) any { return map[string]string{} })
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	_, err := client.Request(ctx, &Message{Type: dispatchTypeA, To: []string{"did:web:bob"}}, WithRetry(3, 50*time.Millisecond))
+	if err != nil {
+		t.Fatalf("Request() error: %v", err)
+	}
+
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	if len(fake.sent) != 2 || !bytes.Equal(fake.sent[0], fake.sent[1]) {
+		t.Errorf("sent %d messages, want the same message twice", len(fake.sent))
+	}
+}
+
+func TestClient_Request_WithRetry_ResendsAfterReconnect(t *testing.T) {
+	fake := &fakeTransport{}
+	client := newTestClient(t, fake, Config{}, nil)
+	connectTestClient(t, client)
+
+	result := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		_, err := client.Request(ctx, &Message{Type: dispatchTypeA, To: []string{"did:web:bob"}}, WithRetry(2, 0))
+		result <- err
+	}()
+	waitForSent(t, fake, 1)
+
+	// The connection drops before the response arrives; the request is
+	// resent once the connection is restored.
+	fake.setDown(true)
+	fake.drop(errors.New("connection reset"))
+	fake.setDown(false)
+	fake.reconnect()
+
+	ids := waitForSent(t, fake, 2)
+	if ids[0] != ids[1] {
+		t.Errorf("resent ID %q, want the original %q", ids[1], ids[0])
+	}
+	go answerRequest(fake, func(json.RawMessage) any { return map[string]string{} })
+	if err := <-result; err != nil {
+		t.Fatalf("Request() error: %v", err)
+	}
+}
+
+func TestClient_Request_WithRetry_ReportsAttempts(t *testing.T) {
+	fake := &fakeTransport{}
+	client := newTestClient(t, fake, Config{}, nil)
+	connectTestClient(t, client)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	_, err := client.Request(ctx, &Message{ID: "req-1", Type: dispatchTypeA, To: []string{"did:web:bob"}}, WithRetry(2, 20*time.Millisecond))
+
+	var reqErr *RequestError
+	if !errors.As(err, &reqErr) {
+		t.Fatalf("Request() error = %v, want a *RequestError", err)
+	}
+	if reqErr.Attempts != 3 || reqErr.MessageID != "req-1" || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("RequestError = %+v, want 3 attempts ending in the deadline", reqErr)
+	}
+	if n := len(fake.sentIDs()); n != 3 {
+		t.Errorf("sent %d times, want 3", n)
+	}
+}
@@ -1,9 +1,11 @@
 package layr8
 
 import (
+	"crypto/tls"
 	"fmt"
 	"net/url"
 	"os"
+	"slices"
 	"strings"
 )
 
@@ -13,19 +15,122 @@ type Config struct {
 	// Fallback: LAYR8_NODE_URL environment variable.
 	NodeURL string
 
+	// NodeURLs lists cloud-nodes to fail over between. If NodeURL is also set,
+	// it is tried as the first node. After resolution NodeURL holds the first
+	// entry and NodeURLs the full list.
+	NodeURLs []string
+
+	// NodeSelection chooses the node for each connection attempt when
+	// several are configured. Default: SelectOrdered.
+	NodeSelection NodeSelection
+
 	// APIKey is the authentication key for the cloud-node.
 	// Fallback: LAYR8_API_KEY environment variable.
 	APIKey string
 
+	// Credentials supplies the API key on every connection attempt and REST
+	// request, allowing keys to be rotated without restarting the process.
+	// Takes precedence over APIKey. Default: StaticCredentials(APIKey).
+	Credentials CredentialProvider
+
+	// APIKeyPlacement selects how the API key is sent on the WebSocket
+	// connection. Default: APIKeyInQuery, which works with all nodes but
+	// exposes the key in proxy and access logs. The REST client always uses
+	// the x-api-key header.
+	APIKeyPlacement APIKeyPlacement
+
 	// AgentDID is the DID identity of this agent.
 	// If empty, an ephemeral DID is created on Connect().
 	// Fallback: LAYR8_AGENT_DID environment variable.
 	AgentDID string
+
+	// Transport creates the transport used to reach the cloud-node.
+	// If nil, NewPhoenixTransport is used.
+	Transport TransportFactory
+
+	// ReconnectPolicy controls backoff, jitter and limits for automatic
+	// reconnection. The zero value retries forever from 1s up to 30s.
+	ReconnectPolicy ReconnectPolicy
+
+	// Heartbeat controls the heartbeat interval, dead-connection detection
+	// and round-trip latency reporting.
+	Heartbeat HeartbeatPolicy
+
+	// OutboundQueue, if set, buffers outbound messages (including handler
+	// responses) while disconnected and flushes them in order after reconnect.
+	// If nil, sends fail with ErrNotConnected during a disconnect.
+	OutboundQueue *OutboundQueue
+
+	// MaxConcurrentHandlers limits how many handler invocations run at once
+	// across all handlers. 0 means unlimited. See also WithConcurrency.
+	MaxConcurrentHandlers int
+
+	// Backpressure is the default policy for inbound messages that arrive
+	// while a concurrency limit is reached. Default: BackpressureQueue.
+	Backpressure Backpressure
+
+	// MaxQueuedMessages caps how many inbound messages wait for a free slot
+	// across all handlers. Messages arriving at a full queue are withheld,
+	// as with BackpressureWithholdAck, so the cloud-node redelivers them.
+	// Default: 10000.
+	MaxQueuedMessages int
+
+	// Unhandled decides what happens to inbound messages that cannot be
+	// parsed or that no handler matches. Default: UnhandledLeave, which
+	// leaves them unacknowledged for redelivery.
+	Unhandled UnhandledPolicy
+
+	// MaxDeliveryAttempts dead-letters a message once a WithManualAck handler
+	// has failed on it this many times: it is stored in DeadLetters and
+	// acknowledged, so the cloud-node stops redelivering it. Attempts are
+	// counted per message ID. 0 means messages are redelivered indefinitely.
+	MaxDeliveryAttempts int
+
+	// DeadLetters receives messages given up on under UnhandledDeadLetter
+	// or MaxDeliveryAttempts. Required with either.
+	DeadLetters DeadLetterSink
+
+	// Deduplication, if set, acknowledges inbound messages whose ID was
+	// already processed without running the handler again.
+	// If nil, every delivery reaches the handler.
+	Deduplication *Deduplication
+
+	// AckBatching coalesces acknowledgments of inbound messages into fewer
+	// frames. Pending acks are sent before Close and Shutdown return.
+	// The zero value acknowledges every message immediately.
+	AckBatching AckBatching
+
+	// TLSConfig customizes TLS for both the WebSocket and REST connections,
+	// e.g. private root CAs (RootCAs) or client certificates for mTLS
+	// (Certificates). If nil, Go's defaults are used.
+	TLSConfig *tls.Config
+
+	// ProxyURL routes WebSocket and REST connections through an HTTP or
+	// SOCKS5 proxy, e.g. "http://proxy.corp:3128" or "socks5://127.0.0.1:1080".
+	// Proxies reached over TLS ("https://") are not supported.
+	// If empty, connections are made directly.
+	ProxyURL string
+
+	// NetDialContext, if set, dials the underlying TCP connections (to the
+	// node, or to the proxy when ProxyURL is set). *.localhost hosts are
+	// resolved to 127.0.0.1 before it is called.
+	NetDialContext DialContextFunc
 }
 
+// APIKeyPlacement selects where the API key is sent when opening the
+// WebSocket connection.
+type APIKeyPlacement int
+
+const (
+	APIKeyInQuery       APIKeyPlacement = iota // api_key Phoenix connect param in the URL query (default; supported by older nodes)
+	APIKeyInHeader                             // x-api-key handshake header, as used by the REST API
+	APIKeyInBearer                             // "Authorization: Bearer <key>" handshake header
+	APIKeyInJoinPayload                        // api_key field of the phx_join payload; the node must read it from the channel join, not the socket connect
+)
+
 // resolveConfig fills empty fields from environment variables and validates required fields.
 func resolveConfig(cfg Config) (Config, error) {
-	if cfg.NodeURL == "" {
+	if cfg.NodeURL == "" && len(cfg.NodeURLs) == 0 {
 		cfg.NodeURL = os.Getenv("LAYR8_NODE_URL")
 	}
 	if cfg.APIKey == "" {
@@ -35,24 +140,90 @@ func resolveConfig(cfg Config) (Config, error) {
 		cfg.AgentDID = os.Getenv("LAYR8_AGENT_DID")
 	}
 
-	if cfg.NodeURL == "" {
+	if cfg.NodeURL == "" && len(cfg.NodeURLs) == 0 {
 		return cfg, fmt.Errorf("NodeURL is required (set in Config or LAYR8_NODE_URL env)")
 	}
 
-	// Normalize HTTP(S) URLs to WebSocket scheme.
-	// In production, the /plugin_socket endpoint serves WebSocket over HTTPS.
-	if rest, ok := strings.CutPrefix(cfg.NodeURL, "https://"); ok {
-		cfg.NodeURL = "wss://" + rest
-	} else if rest, ok := strings.CutPrefix(cfg.NodeURL, "http://"); ok {
-		cfg.NodeURL = "ws://" + rest
+	var nodes []string
+	for _, u := range append([]string{cfg.NodeURL}, cfg.NodeURLs...) {
+		if u == "" {
+			continue
+		}
+		u = normalizeNodeURL(u)
+		if !slices.Contains(nodes, u) {
+			nodes = append(nodes, u)
+		}
 	}
-	if cfg.APIKey == "" {
-		return cfg, fmt.Errorf("APIKey is required (set in Config or LAYR8_API_KEY env)")
+	if len(nodes) == 0 {
+		return cfg, fmt.Errorf("NodeURLs must contain at least one non-empty URL")
+	}
+	cfg.NodeURL, cfg.NodeURLs = nodes[0], nodes
+	if cfg.NodeSelection < SelectOrdered || cfg.NodeSelection > SelectHealthWeighted {
+		return cfg, fmt.Errorf("NodeSelection: unknown value %d", cfg.NodeSelection)
+	}
+	if cfg.Credentials == nil {
+		if cfg.APIKey == "" {
+			return cfg, fmt.Errorf("APIKey is required (set in Config or LAYR8_API_KEY env, or set Credentials)")
+		}
+		cfg.Credentials = StaticCredentials(cfg.APIKey)
+	}
+	if cfg.APIKeyPlacement < APIKeyInQuery || cfg.APIKeyPlacement > APIKeyInJoinPayload {
+		return cfg, fmt.Errorf("APIKeyPlacement: unknown value %d", cfg.APIKeyPlacement)
+	}
+	if err := cfg.ReconnectPolicy.validate(); err != nil {
+		return cfg, err
+	}
+	if err := cfg.Heartbeat.validate(); err != nil {
+		return cfg, err
+	}
+	if err := cfg.AckBatching.validate(); err != nil {
+		return cfg, err
+	}
+	if cfg.OutboundQueue != nil && cfg.OutboundQueue.MaxAge < 0 {
+		return cfg, fmt.Errorf("OutboundQueue.MaxAge must not be negative")
+	}
+	if cfg.MaxConcurrentHandlers < 0 {
+		return cfg, fmt.Errorf("MaxConcurrentHandlers must not be negative")
+	}
+	if cfg.MaxQueuedMessages < 0 {
+		return cfg, fmt.Errorf("MaxQueuedMessages must not be negative")
+	}
+	if cfg.Backpressure < BackpressureQueue || cfg.Backpressure > BackpressureWithholdAck {
+		return cfg, fmt.Errorf("Backpressure: unknown value %d", cfg.Backpressure)
+	}
+	if cfg.Unhandled < UnhandledLeave || cfg.Unhandled > UnhandledDeadLetter {
+		return cfg, fmt.Errorf("Unhandled: unknown value %d", cfg.Unhandled)
+	}
+	if cfg.Unhandled == UnhandledDeadLetter && cfg.DeadLetters == nil {
+		return cfg, fmt.Errorf("DeadLetters is required with UnhandledDeadLetter")
+	}
+	if cfg.MaxDeliveryAttempts < 0 {
+		return cfg, fmt.Errorf("MaxDeliveryAttempts must not be negative")
+	}
+	if cfg.MaxDeliveryAttempts > 0 && cfg.DeadLetters == nil {
+		return cfg, fmt.Errorf("DeadLetters is required with MaxDeliveryAttempts")
+	}
+	if cfg.ProxyURL != "" {
+		if _, err := parseProxyURL(cfg.ProxyURL); err != nil {
+			return cfg, err
+		}
 	}
 
 	return cfg, nil
 }
 
+// normalizeNodeURL converts HTTP(S) URLs to the WebSocket scheme.
+// In production, the /plugin_socket endpoint serves WebSocket over HTTPS.
+func normalizeNodeURL(u string) string {
+	if rest, ok := strings.CutPrefix(u, "https://"); ok {
+		return "wss://" + rest
+	}
+	if rest, ok := strings.CutPrefix(u, "http://"); ok {
+		return "ws://" + rest
+	}
+	return u
+}
+
 // restURLFromWebSocket derives the REST API base URL from a WebSocket URL.
 // ws://alice-test.localhost/plugin_socket/websocket → http://alice-test.localhost
 // wss://alice-test.localhost/plugin_socket/websocket → https://alice-test.localhost
@@ -1,8 +1,10 @@
 package layr8
 
 import (
+	"context"
 	"os"
 	"testing"
+	"time"
 )
 
 func TestResolveConfig_ExplicitValues(t *testing.T) {
@@ -163,3 +165,140 @@ func TestRestURLFromWebSocket(t *testing.T) {
 		})
 	}
 }
+
+func TestResolveConfig_InvalidReconnectPolicy(t *testing.T) {
+	_, err := resolveConfig(Config{
+		NodeURL:         "ws://localhost:4000",
+		APIKey:          "test-key",
+		ReconnectPolicy: ReconnectPolicy{Multiplier: 0.5},
+	})
+	if err == nil {
+		t.Fatal("resolveConfig() should reject a shrinking backoff multiplier")
+	}
+}
+
+func TestResolveConfig_NegativeQueueMaxAge(t *testing.T) {
+	_, err := resolveConfig(Config{
+		NodeURL:       "ws://localhost:4000",
+		APIKey:        "test-key",
+		OutboundQueue: &OutboundQueue{MaxAge: -time.Second},
+	})
+	if err == nil {
+		t.Fatal("resolveConfig() should reject a negative OutboundQueue.MaxAge")
+	}
+}
+
+func TestResolveConfig_InvalidConcurrency(t *testing.T) {
+	for _, cfg := range []Config{
+		{MaxConcurrentHandlers: -1},
+		{MaxQueuedMessages: -1},
+		{Backpressure: Backpressure(42)},
+	} {
+		cfg.NodeURL = "ws://localhost:4000"
+		cfg.APIKey = "test-key"
+		if _, err := resolveConfig(cfg); err == nil {
+			t.Errorf("resolveConfig(%+v) should fail", cfg)
+		}
+	}
+}
+
+func TestResolveConfig_InvalidProxyURL(t *testing.T) {
+	for _, proxyURL := range []string{"ftp://proxy:21", "https://proxy:443", "http://", "://bad"} {
+		_, err := resolveConfig(Config{
+			NodeURL:  "ws://localhost:4000",
+			APIKey:   "test-key",
+			ProxyURL: proxyURL,
+		})
+		if err == nil {
+			t.Errorf("resolveConfig(ProxyURL=%q) should fail", proxyURL)
+		}
+	}
+}
+
+func TestResolveConfig_InvalidAPIKeyPlacement(t *testing.T) {
+	_, err := resolveConfig(Config{
+		NodeURL:         "ws://localhost:4000",
+		APIKey:          "test-key",
+		APIKeyPlacement: APIKeyPlacement(42),
+	})
+	if err == nil {
+		t.Fatal("resolveConfig() should reject an unknown APIKeyPlacement")
+	}
+}
+
+func TestResolveConfig_CredentialsWithoutAPIKey(t *testing.T) {
+	t.Setenv("LAYR8_API_KEY", "")
+	cfg, err := resolveConfig(Config{
+		NodeURL:     "ws://localhost:4000",
+		Credentials: StaticCredentials("from-provider"),
+	})
+	if err != nil {
+		t.Fatalf("resolveConfig() error: %v", err)
+	}
+	if key, _ := cfg.Credentials.APIKey(context.Background()); key != "from-provider" {
+		t.Errorf("Credentials.APIKey() = %q, want %q", key, "from-provider")
+	}
+}
+
+func TestResolveConfig_NodeURLs(t *testing.T) {
+	cfg, err := resolveConfig(Config{
+		NodeURL:  "https://primary.example/plugin_socket/websocket",
+		NodeURLs: []string{"wss://primary.example/plugin_socket/websocket", "http://backup.example/plugin_socket/websocket"},
+		APIKey:   "test-key",
+	})
+	if err != nil {
+		t.Fatalf("resolveConfig() error: %v", err)
+	}
+	want := []string{"wss://primary.example/plugin_socket/websocket", "ws://backup.example/plugin_socket/websocket"}
+	if len(cfg.NodeURLs) != 2 || cfg.NodeURLs[0] != want[0] || cfg.NodeURLs[1] != want[1] {
+		t.Errorf("NodeURLs = %v, want %v", cfg.NodeURLs, want)
+	}
+	if cfg.NodeURL != want[0] {
+		t.Errorf("NodeURL = %q, want %q", cfg.NodeURL, want[0])
+	}
+
+	if _, err := resolveConfig(Config{NodeURLs: want, APIKey: "test-key", NodeSelection: NodeSelection(9)}); err == nil {
+		t.Error("resolveConfig() should reject an unknown NodeSelection")
+	}
+}
+
+func TestResolveConfig_UnhandledPolicy(t *testing.T) {
+	base := Config{NodeURL: "ws://localhost:4000", APIKey: "k", AgentDID: "did:web:a"}
+
+	cfg := base
+	cfg.Unhandled = UnhandledPolicy(42)
+	if _, err := resolveConfig(cfg); err == nil {
+		t.Error("resolveConfig() should reject an unknown Unhandled policy")
+	}
+
+	cfg = base
+	cfg.Unhandled = UnhandledDeadLetter
+	if _, err := resolveConfig(cfg); err == nil {
+		t.Error("resolveConfig() should require DeadLetters with UnhandledDeadLetter")
+	}
+}
+
+func TestResolveConfig_MaxDeliveryAttempts(t *testing.T) {
+	base := Config{NodeURL: "ws://localhost:4000", APIKey: "k", AgentDID: "did:web:a"}
+
+	cfg := base
+	cfg.MaxDeliveryAttempts = -1
+	if _, err := resolveConfig(cfg); err == nil {
+		t.Error("resolveConfig() should reject a negative MaxDeliveryAttempts")
+	}
+
+	cfg = base
+	cfg.MaxDeliveryAttempts = 3
+	if _, err := resolveConfig(cfg); err == nil {
+		t.Error("resolveConfig() should require DeadLetters with MaxDeliveryAttempts")
+	}
+}
+
+func TestResolveConfig_InvalidAckBatching(t *testing.T) {
+	for _, p := range []AckBatching{{Window: -time.Millisecond}, {MaxSize: -1}} {
+		_, err := resolveConfig(Config{NodeURL: "ws://localhost:4000", APIKey: "k", AgentDID: "did:web:a", AckBatching: p})
+		if err == nil {
+			t.Errorf("resolveConfig(%+v) should fail", p)
+		}
+	}
+}
@@ -0,0 +1,283 @@
+package layr8
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DeadLetter is an inbound message the agent gave up on, together with the
+// reason. Payload is the raw envelope as received from the cloud-node.
+type DeadLetter struct {
+	MessageID string    `json:"message_id,omitempty"`
+	Type      string    `json:"type,omitempty"`
+	From      string    `json:"from,omitempty"`
+	Kind      ErrorKind `json:"kind"`            // why the message was dead-lettered
+	Error     string    `json:"error,omitempty"` // text of the underlying error
+	Attempts  int       `json:"attempts,omitempty"`
+	Payload   []byte    `json:"payload"`
+	At        time.Time `json:"at"`
+}
+
+// DeadLetterSink stores dead-lettered messages for inspection and replay.
+// Implementations must be safe for concurrent use.
+type DeadLetterSink interface {
+	// Put stores a dead letter. The message is acknowledged only after Put
+	// succeeds; if Put fails it stays unacknowledged, so the cloud-node
+	// redelivers it. Put is called from the transport's read loop and
+	// handler goroutines and should return promptly.
+	Put(ctx context.Context, dl DeadLetter) error
+
+	// List returns the stored dead letters, oldest first.
+	List(ctx context.Context) ([]DeadLetter, error)
+
+	// Remove deletes the dead letter for a message ID, e.g. after a
+	// successful Client.Replay. Removing an unknown ID is not an error.
+	Remove(ctx context.Context, messageID string) error
+}
+
+// --- In-memory sink ---
+
+type memoryDeadLetterSink struct {
+	mu      sync.Mutex
+	letters []DeadLetter
+}
+
+// NewMemoryDeadLetterSink returns a DeadLetterSink that keeps dead letters in
+// memory. They are lost when the process exits.
+func NewMemoryDeadLetterSink() DeadLetterSink {
+	return &memoryDeadLetterSink{}
+}
+
+func (s *memoryDeadLetterSink) Put(ctx context.Context, dl DeadLetter) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.letters = append(s.letters, dl)
+	return nil
+}
+
+func (s *memoryDeadLetterSink) List(ctx context.Context) ([]DeadLetter, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]DeadLetter, len(s.letters))
+	copy(out, s.letters)
+	return out, nil
+}
+
+func (s *memoryDeadLetterSink) Remove(ctx context.Context, messageID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.remove(messageID)
+	return nil
+}
+
+// remove drops the letters for messageID and reports whether any were found.
+// Must be called with s.mu held.
+func (s *memoryDeadLetterSink) remove(messageID string) bool {
+	kept := s.letters[:0]
+	for _, dl := range s.letters {
+		if dl.MessageID != messageID {
+			kept = append(kept, dl)
+		}
+	}
+	found := len(kept) < len(s.letters)
+	clear(s.letters[len(kept):])
+	s.letters = kept
+	return found
+}
+
+// --- File sink ---
+
+type fileDeadLetterSink struct {
+	memoryDeadLetterSink
+	file jsonlFile
+}
+
+// NewFileDeadLetterSink returns a DeadLetterSink persisted as JSON lines at
+// path. Dead letters already in the file are loaded, so they survive restarts.
+func NewFileDeadLetterSink(path string) (DeadLetterSink, error) {
+	s := &fileDeadLetterSink{file: jsonlFile{path: path, name: "dead-letter file"}}
+	err := s.file.load(func(line []byte) error {
+		var dl DeadLetter
+		if err := json.Unmarshal(line, &dl); err != nil {
+			return err
+		}
+		s.letters = append(s.letters, dl)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *fileDeadLetterSink) Put(ctx context.Context, dl DeadLetter) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.file.append(dl); err != nil {
+		return err
+	}
+	s.letters = append(s.letters, dl)
+	return nil
+}
+
+func (s *fileDeadLetterSink) Remove(ctx context.Context, messageID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.remove(messageID) {
+		return nil
+	}
+	return s.file.rewrite(func(enc *json.Encoder) error {
+		for _, dl := range s.letters {
+			if err := enc.Encode(dl); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// --- Client integration ---
+
+// maxTrackedDeliveries bounds how many message IDs deliveryAttempts
+// remembers; the oldest are forgotten first.
+const maxTrackedDeliveries = 10000
+
+// deliveryAttempts counts failed handler runs per message ID, so a message
+// that keeps failing can be dead-lettered after Config.MaxDeliveryAttempts.
+type deliveryAttempts struct {
+	mu     sync.Mutex
+	counts map[string]int
+	order  []string // IDs in order of first failure; may hold forgotten IDs
+}
+
+func newDeliveryAttempts() *deliveryAttempts {
+	return &deliveryAttempts{counts: make(map[string]int)}
+}
+
+// fail records a failed attempt for id and returns the number so far.
+func (a *deliveryAttempts) fail(id string) int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	n, seen := a.counts[id]
+	a.counts[id] = n + 1
+	if !seen {
+		a.order = append(a.order, id)
+		for len(a.counts) > maxTrackedDeliveries {
+			delete(a.counts, a.order[0])
+			a.order = a.order[1:]
+		}
+		if len(a.order) > 2*maxTrackedDeliveries {
+			a.compact()
+		}
+	}
+	return n + 1
+}
+
+// forget drops the count for id after it succeeded or was dead-lettered.
+func (a *deliveryAttempts) forget(id string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.counts, id)
+}
+
+// compact drops forgotten IDs from order. Must be called with a.mu held.
+func (a *deliveryAttempts) compact() {
+	kept := make([]string, 0, len(a.counts))
+	for _, id := range a.order {
+		if _, ok := a.counts[id]; ok {
+			kept = append(kept, id)
+		}
+	}
+	a.order = kept
+}
+
+// handlerFailed records a failed run of a manual-ack handler. Once the
+// message has failed Config.MaxDeliveryAttempts times it is dead-lettered
+// and acknowledged, so the cloud-node stops redelivering it; handlerFailed
+// then returns true.
+func (c *Client) handlerFailed(msg *Message, handlerErr error) bool {
+	limit := c.cfg.MaxDeliveryAttempts
+	if limit == 0 || msg.ID == "" {
+		return false
+	}
+	attempts := c.deliveries.fail(msg.ID)
+	if attempts < limit {
+		return false
+	}
+
+	err := c.cfg.DeadLetters.Put(c.ctx, DeadLetter{
+		MessageID: msg.ID,
+		Type:      msg.Type,
+		From:      msg.From,
+		Kind:      ErrMaxDeliveries,
+		Error:     handlerErr.Error(),
+		Attempts:  attempts,
+		Payload:   msg.raw,
+		At:        time.Now(),
+	})
+	if err != nil {
+		c.onError(SDKError{
+			Kind:      ErrDeadLetter,
+			MessageID: msg.ID,
+			Type:      msg.Type,
+			From:      msg.From,
+			Cause:     err,
+			Timestamp: time.Now(),
+		})
+		return false // counted again on the next redelivery
+	}
+	c.deliveries.forget(msg.ID)
+	c.ack(msg.ID)
+	c.onError(SDKError{
+		Kind:      ErrMaxDeliveries,
+		MessageID: msg.ID,
+		Type:      msg.Type,
+		From:      msg.From,
+		Cause:     fmt.Errorf("dead-lettered after %d attempts: %w", attempts, handlerErr),
+		Timestamp: time.Now(),
+	})
+	return true
+}
+
+// Replay runs the handler for a dead-lettered message again, synchronously
+// and with ctx as the handler context. A response returned by the handler is
+// sent as usual. On success the letter is removed from Config.DeadLetters;
+// on failure the handler's error is returned and the letter is kept.
+//
+// Replayed messages were already acknowledged, so Message.Ack is a no-op and
+// no problem report is sent to the original sender.
+func (c *Client) Replay(ctx context.Context, dl DeadLetter) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("handler panic: %v", r)
+		}
+	}()
+
+	msg, err := parseDIDComm(dl.Payload)
+	if err != nil {
+		return err
+	}
+	msg.raw = dl.Payload
+
+	entry, ok := c.registry.lookup(msg.Type)
+	if !ok {
+		return fmt.Errorf("no handler for %s", msg.Type)
+	}
+
+	resp, err := c.chain(entry)(context.WithValue(ctx, messageContextKey{}, msg), msg)
+	if err != nil {
+		return err
+	}
+	if resp != nil {
+		if err := c.sendMessage(c.fillResponse(msg, resp)); err != nil {
+			return fmt.Errorf("send replay response: %w", err)
+		}
+	}
+	if c.cfg.DeadLetters != nil {
+		return c.cfg.DeadLetters.Remove(ctx, dl.MessageID)
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+package layr8
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestMemoryDeadLetterSink(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryDeadLetterSink()
+	for _, id := range []string{"1", "2", "3"} {
+		s.Put(ctx, DeadLetter{MessageID: id})
+	}
+	if err := s.Remove(ctx, "2"); err != nil {
+		t.Fatalf("Remove() error: %v", err)
+	}
+
+	letters, _ := s.List(ctx)
+	var ids []string
+	for _, dl := range letters {
+		ids = append(ids, dl.MessageID)
+	}
+	if !slices.Equal(ids, []string{"1", "3"}) {
+		t.Errorf("List() IDs = %v, want [1 3]", ids)
+	}
+}
+
+func TestFileDeadLetterSink_Persists(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "dead-letters.jsonl")
+
+	s, err := NewFileDeadLetterSink(path)
+	if err != nil {
+		t.Fatalf("NewFileDeadLetterSink() error: %v", err)
+	}
+	for _, id := range []string{"1", "2", "3"} {
+		dl := DeadLetter{MessageID: id, Kind: ErrMaxDeliveries, Error: "boom", Payload: []byte(`{"id":"` + id + `"}`)}
+		if err := s.Put(ctx, dl); err != nil {
+			t.Fatalf("Put() error: %v", err)
+		}
+	}
+	if err := s.Remove(ctx, "1"); err != nil {
+		t.Fatalf("Remove() error: %v", err)
+	}
+
+	// Reopen, as after a process restart.
+	s, err = NewFileDeadLetterSink(path)
+	if err != nil {
+		t.Fatalf("reopen error: %v", err)
+	}
+	letters, _ := s.List(ctx)
+	if len(letters) != 2 {
+		t.Fatalf("List() after reopen = %d letters, want 2", len(letters))
+	}
+	if dl := letters[0]; dl.MessageID != "2" || dl.Kind != ErrMaxDeliveries || dl.Error != "boom" || string(dl.Payload) != `{"id":"2"}` {
+		t.Errorf("letters[0] = %+v, want message 2", dl)
+	}
+}
+
+// failNTimes returns a handler that fails its first n runs and then answers
+// with a dispatchTypeB response.
+func failNTimes(n int, runs chan<- string) HandlerFunc {
+	return func(msg *Message) (*Message, error) {
+		runs <- msg.ID
+		if n > 0 {
+			n--
+			return nil, errors.New("database unavailable")
+		}
+		return &Message{Type: dispatchTypeB, Body: map[string]string{}}, nil
+	}
+}
+
+func TestClient_MaxDeliveryAttempts(t *testing.T) {
+	fake := &fakeTransport{}
+	sink := NewMemoryDeadLetterSink()
+	client := newTestClient(t, fake, Config{MaxDeliveryAttempts: 3, DeadLetters: sink}, nil)
+	runs := make(chan string, 10)
+	client.Handle(dispatchTypeA, failNTimes(10, runs), WithManualAck())
+	connectTestClient(t, client)
+
+	// The cloud-node redelivers the unacknowledged message.
+	for attempt := 1; attempt <= 3; attempt++ {
+		fake.handler(inboundPayload("m1", dispatchTypeA, ""))
+		<-runs
+		waitForSent(t, fake, attempt) // problem report for each failure
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for len(fake.ackedIDs()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if acks := fake.ackedIDs(); !slices.Equal(acks, []string{"m1"}) {
+		t.Errorf("acks = %v, want m1 acked after the third failure", acks)
+	}
+
+	letters, _ := sink.List(context.Background())
+	if len(letters) != 1 {
+		t.Fatalf("dead letters = %d, want 1", len(letters))
+	}
+	dl := letters[0]
+	if dl.MessageID != "m1" || dl.Kind != ErrMaxDeliveries || dl.Attempts != 3 || dl.Error != "database unavailable" {
+		t.Errorf("dead letter = %+v", dl)
+	}
+	if !json.Valid(dl.Payload) || string(dl.Payload) != string(inboundPayload("m1", dispatchTypeA, "")) {
+		t.Errorf("Payload = %s, want the raw envelope", dl.Payload)
+	}
+}
+
+func TestClient_MaxDeliveryAttempts_ResetOnSuccess(t *testing.T) {
+	fake := &fakeTransport{}
+	sink := NewMemoryDeadLetterSink()
+	client := newTestClient(t, fake, Config{MaxDeliveryAttempts: 2, DeadLetters: sink}, nil)
+	runs := make(chan string, 10)
+	client.Handle(dispatchTypeA, failNTimes(1, runs), WithManualAck())
+	connectTestClient(t, client)
+
+	fake.handler(inboundPayload("m1", dispatchTypeA, "")) // fails
+	<-runs
+	fake.handler(inboundPayload("m1", dispatchTypeA, "")) // succeeds
+	<-runs
+	waitForSent(t, fake, 2)
+
+	if letters, _ := sink.List(context.Background()); len(letters) != 0 {
+		t.Errorf("dead letters = %v, want none", letters)
+	}
+	if n := client.deliveries.fail("m1"); n != 1 {
+		t.Errorf("next failure counted as attempt %d, want 1 after a success", n)
+	}
+}
+
+func TestClient_Replay(t *testing.T) {
+	fake := &fakeTransport{}
+	sink := NewMemoryDeadLetterSink()
+	client := newTestClient(t, fake, Config{MaxDeliveryAttempts: 1, DeadLetters: sink}, nil)
+	runs := make(chan string, 10)
+	client.Handle(dispatchTypeA, failNTimes(2, runs), WithManualAck())
+	connectTestClient(t, client)
+
+	fake.handler(inboundPayload("m1", dispatchTypeA, ""))
+	<-runs
+	deadline := time.Now().Add(2 * time.Second)
+	var letters []DeadLetter
+	for len(letters) == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+		letters, _ = sink.List(context.Background())
+	}
+	if len(letters) != 1 {
+		t.Fatalf("dead letters = %d, want 1", len(letters))
+	}
+
+	// The handler still fails: the letter is kept.
+	if err := client.Replay(context.Background(), letters[0]); err == nil {
+		t.Fatal("Replay() should return the handler error")
+	}
+	if kept, _ := sink.List(context.Background()); len(kept) != 1 {
+		t.Fatalf("dead letters after failed replay = %d, want 1", len(kept))
+	}
+
+	if err := client.Replay(context.Background(), letters[0]); err != nil {
+		t.Fatalf("Replay() error: %v", err)
+	}
+	if kept, _ := sink.List(context.Background()); len(kept) != 0 {
+		t.Errorf("dead letters after replay = %d, want 0", len(kept))
+	}
+	sent := lastSent(t, fake, 2) // problem report, then the replayed response
+	var msgType, thid string
+	json.Unmarshal(sent["type"], &msgType)
+	json.Unmarshal(sent["thid"], &thid)
+	if msgType != dispatchTypeB || thid != "m1" {
+		t.Errorf("replay response = type %q thid %q, want %q on thread m1", msgType, thid, dispatchTypeB)
+	}
+}
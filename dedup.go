@@ -0,0 +1,291 @@
+package layr8
+
+import (
+	"container/list"
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// Deduplication enables skipping inbound messages whose ID was already
+// processed, e.g. when the cloud-node redelivers after a reconnect.
+// Duplicates are acknowledged without running the handler.
+type Deduplication struct {
+	// Store remembers processed message IDs.
+	// Default: NewMemorySeenStore(10000, time.Hour).
+	Store SeenStore
+
+	// ReplayResponses answers a duplicate with the response the handler
+	// returned for the original, sent again unchanged (same message ID).
+	// Useful for request/response protocols whose senders retry.
+	ReplayResponses bool
+}
+
+// SeenStore records processed inbound message IDs for Deduplication.
+// Implementations must be safe for concurrent use.
+type SeenStore interface {
+	// Add records id as processed, with the serialized response sent for it
+	// (nil if none or not kept). Adding an existing id replaces its response.
+	Add(id string, response []byte) error
+
+	// Get reports whether id was processed and returns its response.
+	Get(id string) (response []byte, seen bool, err error)
+}
+
+// --- In-memory store ---
+
+type seenEntry struct {
+	ID       string    `json:"id"`
+	Response []byte    `json:"response,omitempty"`
+	At       time.Time `json:"at"`
+}
+
+type memorySeenStore struct {
+	mu       sync.Mutex
+	capacity int
+	ttl      time.Duration
+	order    *list.List // of *seenEntry, most recently used first
+	index    map[string]*list.Element
+}
+
+// NewMemorySeenStore returns an in-memory SeenStore that remembers at most
+// capacity IDs, evicting the least recently used first, each for at most ttl.
+// A capacity <= 0 defaults to 10000; a ttl <= 0 means IDs don't expire.
+func NewMemorySeenStore(capacity int, ttl time.Duration) SeenStore {
+	return newMemorySeenStore(capacity, ttl)
+}
+
+func newMemorySeenStore(capacity int, ttl time.Duration) *memorySeenStore {
+	if capacity <= 0 {
+		capacity = 10000
+	}
+	return &memorySeenStore{
+		capacity: capacity,
+		ttl:      ttl,
+		order:    list.New(),
+		index:    make(map[string]*list.Element),
+	}
+}
+
+func (s *memorySeenStore) Add(id string, response []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.put(seenEntry{ID: id, Response: response, At: time.Now()})
+	return nil
+}
+
+func (s *memorySeenStore) Get(id string) ([]byte, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	el, ok := s.index[id]
+	if !ok {
+		return nil, false, nil
+	}
+	e := el.Value.(*seenEntry)
+	if s.expired(*e) {
+		s.order.Remove(el)
+		delete(s.index, id)
+		return nil, false, nil
+	}
+	s.order.MoveToFront(el)
+	return e.Response, true, nil
+}
+
+// put inserts or replaces an entry and evicts beyond capacity. Must be
+// called with s.mu held.
+func (s *memorySeenStore) put(e seenEntry) {
+	if el, ok := s.index[e.ID]; ok {
+		el.Value = &e
+		s.order.MoveToFront(el)
+		return
+	}
+	s.index[e.ID] = s.order.PushFront(&e)
+	for s.order.Len() > s.capacity {
+		oldest := s.order.Back()
+		s.order.Remove(oldest)
+		delete(s.index, oldest.Value.(*seenEntry).ID)
+	}
+}
+
+func (s *memorySeenStore) expired(e seenEntry) bool {
+	return s.ttl > 0 && time.Since(e.At) > s.ttl
+}
+
+// --- File store ---
+
+type fileSeenStore struct {
+	*memorySeenStore
+	file  jsonlFile
+	lines int // lines in the file, compacted when far above the entry count
+}
+
+// NewFileSeenStore returns a SeenStore persisted as JSON lines at path, with
+// the same capacity and ttl semantics as NewMemorySeenStore. IDs already in
+// the file are loaded, so duplicates are recognized across restarts.
+func NewFileSeenStore(path string, capacity int, ttl time.Duration) (SeenStore, error) {
+	s := &fileSeenStore{
+		memorySeenStore: newMemorySeenStore(capacity, ttl),
+		file:            jsonlFile{path: path, name: "seen store"},
+	}
+	err := s.file.load(func(line []byte) error {
+		var e seenEntry
+		if err := json.Unmarshal(line, &e); err != nil {
+			return err
+		}
+		s.lines++
+		if !s.expired(e) {
+			s.put(e)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *fileSeenStore) Add(id string, response []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e := seenEntry{ID: id, Response: response, At: time.Now()}
+	if err := s.file.append(e); err != nil {
+		return err
+	}
+	s.put(e)
+	s.lines++
+	if s.lines > 2*s.capacity {
+		return s.rewrite()
+	}
+	return nil
+}
+
+// rewrite atomically replaces the file with the live entries. Must be called with s.mu held.
+func (s *fileSeenStore) rewrite() error {
+	n := 0
+	err := s.file.rewrite(func(enc *json.Encoder) error {
+		for el := s.order.Back(); el != nil; el = el.Prev() {
+			e := el.Value.(*seenEntry)
+			if s.expired(*e) {
+				continue
+			}
+			if err := enc.Encode(e); err != nil {
+				return err
+			}
+			n++
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	s.lines = n
+	return nil
+}
+
+// --- Client integration ---
+
+func newSeenStore(d Deduplication) SeenStore {
+	if d.Store == nil {
+		return NewMemorySeenStore(0, time.Hour)
+	}
+	return d.Store
+}
+
+// skipDuplicate reports whether msg was already processed. If so, it is
+// acknowledged and, with ReplayResponses, the original response is resent.
+// Otherwise its ID is claimed until the handler ends; a copy arriving
+// meanwhile is dropped unacknowledged, as the first copy settles the ID.
+// A store error is reported and the message is processed as new.
+func (c *Client) skipDuplicate(msg *Message) bool {
+	if c.seen == nil || msg.ID == "" {
+		return false
+	}
+	response, seen, err := c.seen.Get(msg.ID)
+	if err != nil {
+		c.reportDedupError(msg, err)
+		return false
+	}
+	if !seen {
+		if c.claims.claim(msg.ID) {
+			msg.claimed = true
+			return false
+		}
+		// Another copy is being handled; its outcome decides the ack.
+		return true
+	}
+
+	c.ack(msg.ID)
+	if len(response) > 0 && c.cfg.Deduplication.ReplayResponses {
+		var sent struct {
+			ID string `json:"id"`
+		}
+		json.Unmarshal(response, &sent)
+		c.writeMessage(sent.ID, response)
+	}
+	return true
+}
+
+// claimedIDs holds the IDs of messages between dispatch and the end of their
+// handler, so a copy redelivered meanwhile is not handled a second time.
+type claimedIDs struct {
+	mu  sync.Mutex
+	ids map[string]struct{}
+}
+
+func newClaimedIDs() *claimedIDs {
+	return &claimedIDs{ids: make(map[string]struct{})}
+}
+
+// claim reports whether id was free and is now claimed.
+func (c *claimedIDs) claim(id string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.ids[id]; ok {
+		return false
+	}
+	c.ids[id] = struct{}{}
+	return true
+}
+
+func (c *claimedIDs) release(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.ids, id)
+}
+
+// releaseClaim releases the claim skipDuplicate took on msg, once msg is
+// recorded as processed or was not handled to completion (handler error,
+// Nack, backpressure), so a later redelivery is handled again.
+func (c *Client) releaseClaim(msg *Message) {
+	if msg.claimed {
+		c.claims.release(msg.ID)
+	}
+}
+
+// markProcessed records msg as processed, along with the response sent for
+// it when ReplayResponses is enabled. runHandler calls it once, after the
+// handler returns, for messages that were acknowledged or rejected.
+func (c *Client) markProcessed(msg, resp *Message) {
+	if c.seen == nil || msg.ID == "" {
+		return
+	}
+	var response []byte
+	if resp != nil && c.cfg.Deduplication.ReplayResponses {
+		response, _ = marshalDIDComm(resp)
+	}
+	if err := c.seen.Add(msg.ID, response); err != nil {
+		c.reportDedupError(msg, err)
+	}
+}
+
+func (c *Client) reportDedupError(msg *Message, err error) {
+	c.onError(SDKError{
+		Kind:      ErrDedupStore,
+		MessageID: msg.ID,
+		Type:      msg.Type,
+		From:      msg.From,
+		Cause:     err,
+		Timestamp: time.Now(),
+	})
+}
@@ -0,0 +1,239 @@
+package layr8
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMemorySeenStore_EvictsLeastRecentlyUsed(t *testing.T) {
+	s := NewMemorySeenStore(2, 0)
+	s.Add("1", nil)
+	s.Add("2", nil)
+	s.Get("1") // 2 is now least recently used
+	s.Add("3", nil)
+
+	for id, want := range map[string]bool{"1": true, "2": false, "3": true} {
+		if _, seen, _ := s.Get(id); seen != want {
+			t.Errorf("Get(%q) seen = %v, want %v", id, seen, want)
+		}
+	}
+}
+
+func TestMemorySeenStore_TTL(t *testing.T) {
+	s := NewMemorySeenStore(0, 20*time.Millisecond)
+	s.Add("1", []byte("resp"))
+	if resp, seen, _ := s.Get("1"); !seen || string(resp) != "resp" {
+		t.Fatalf("Get() = %q, %v; want the response", resp, seen)
+	}
+	time.Sleep(40 * time.Millisecond)
+	if _, seen, _ := s.Get("1"); seen {
+		t.Error("Get() after the TTL should report unseen")
+	}
+}
+
+func TestFileSeenStore_Persists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "seen.jsonl")
+
+	s, err := NewFileSeenStore(path, 0, time.Hour)
+	if err != nil {
+		t.Fatalf("NewFileSeenStore() error: %v", err)
+	}
+	s.Add("1", nil)
+	s.Add("2", nil)
+	if err := s.Add("1", []byte(`{"id":"r1"}`)); err != nil {
+		t.Fatalf("Add() error: %v", err)
+	}
+
+	// Reopen, as after a process restart.
+	s, err = NewFileSeenStore(path, 0, time.Hour)
+	if err != nil {
+		t.Fatalf("reopen error: %v", err)
+	}
+	if resp, seen, _ := s.Get("1"); !seen || string(resp) != `{"id":"r1"}` {
+		t.Errorf("Get(1) = %q, %v; want the latest response", resp, seen)
+	}
+	if _, seen, _ := s.Get("2"); !seen {
+		t.Error("Get(2) should be seen after reopen")
+	}
+}
+
+// countingSeenStore counts Add calls to a memory store.
+type countingSeenStore struct {
+	SeenStore
+	mu   sync.Mutex
+	adds int
+}
+
+func (s *countingSeenStore) Add(id string, response []byte) error {
+	s.mu.Lock()
+	s.adds++
+	s.mu.Unlock()
+	return s.SeenStore.Add(id, response)
+}
+
+func (s *countingSeenStore) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.adds
+}
+
+// waitForAdd waits until a message has been recorded, which happens once
+// its handler has returned.
+func (s *countingSeenStore) waitForAdd(t *testing.T) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for s.count() == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if s.count() == 0 {
+		t.Fatal("message was not recorded as processed")
+	}
+}
+
+func TestClient_Deduplication_AcksDuplicate(t *testing.T) {
+	fake := &fakeTransport{}
+	store := &countingSeenStore{SeenStore: NewMemorySeenStore(10, time.Hour)}
+	client := newTestClient(t, fake, Config{Deduplication: &Deduplication{Store: store}}, nil)
+	runs := make(chan string, 10)
+	client.Handle(dispatchTypeA, func(msg *Message) (*Message, error) {
+		runs <- msg.ID
+		return nil, nil
+	})
+	connectTestClient(t, client)
+
+	fake.handler(inboundPayload("m1", dispatchTypeA, ""))
+	<-runs
+	store.waitForAdd(t)
+	fake.handler(inboundPayload("m1", dispatchTypeA, "")) // redelivered
+
+	waitForAcks(t, fake, "m1", "m1")
+	select {
+	case id := <-runs:
+		t.Errorf("handler ran again for duplicate %q", id)
+	case <-time.After(50 * time.Millisecond):
+	}
+	if n := store.count(); n != 1 {
+		t.Errorf("recorded %d times, want once", n)
+	}
+}
+
+func TestClient_Deduplication_RedeliveryWhileRunning(t *testing.T) {
+	fake := &fakeTransport{}
+	client := newTestClient(t, fake, Config{Deduplication: &Deduplication{}}, nil)
+	h := newBlockingHandler()
+	client.Handle(dispatchTypeA, h.fn)
+	connectTestClient(t, client)
+
+	fake.handler(inboundPayload("m1", dispatchTypeA, ""))
+	h.expectStart(t, "m1")
+	fake.handler(inboundPayload("m1", dispatchTypeA, "")) // redelivered after a reconnect
+	h.expectNoStart(t)
+	h.release <- struct{}{}
+
+	// Once recorded, later copies are acked as duplicates.
+	deadline := time.Now().Add(2 * time.Second)
+	for client.HandlerStats()[dispatchTypeA].Running > 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	fake.handler(inboundPayload("m1", dispatchTypeA, ""))
+	h.expectNoStart(t)
+	waitForAcks(t, fake, "m1", "m1")
+}
+
+func TestClient_Deduplication_ReplaysResponse(t *testing.T) {
+	fake := &fakeTransport{}
+	store := &countingSeenStore{SeenStore: NewMemorySeenStore(10, time.Hour)}
+	client := newTestClient(t, fake, Config{Deduplication: &Deduplication{Store: store, ReplayResponses: true}}, nil)
+	runs := 0
+	client.Handle(dispatchTypeA, func(msg *Message) (*Message, error) {
+		runs++
+		return &Message{Type: dispatchTypeB, Body: map[string]int{"run": runs}}, nil
+	})
+	connectTestClient(t, client)
+
+	fake.handler(inboundPayload("m1", dispatchTypeA, ""))
+	waitForSent(t, fake, 1)
+	store.waitForAdd(t)
+	fake.handler(inboundPayload("m1", dispatchTypeA, ""))
+	waitForSent(t, fake, 2)
+
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	if !bytes.Equal(fake.sent[0], fake.sent[1]) {
+		t.Errorf("replayed %s, want the original response %s", fake.sent[1], fake.sent[0])
+	}
+}
+
+func TestClient_Deduplication_FailedManualAckRunsAgain(t *testing.T) {
+	fake := &fakeTransport{}
+	client := newTestClient(t, fake, Config{Deduplication: &Deduplication{}}, nil)
+	runs := make(chan string, 10)
+	client.Handle(dispatchTypeA, failNTimes(1, runs), WithManualAck())
+	connectTestClient(t, client)
+
+	fake.handler(inboundPayload("m1", dispatchTypeA, "")) // fails, not acked
+	<-runs
+	waitForSent(t, fake, 1)
+	fake.handler(inboundPayload("m1", dispatchTypeA, "")) // redelivery is not a duplicate
+	select {
+	case <-runs:
+	case <-time.After(2 * time.Second):
+		t.Fatal("handler should run again for a message that was never acked")
+	}
+}
+
+func TestClient_Deduplication_MissingAckRunsAgain(t *testing.T) {
+	fake := &fakeTransport{}
+	errs := make(chan SDKError, 10)
+	client := newTestClient(t, fake, Config{Deduplication: &Deduplication{}}, func(err SDKError) { errs <- err })
+	runs := make(chan string, 10)
+	client.Handle(dispatchTypeA, func(msg *Message) (*Message, error) {
+		runs <- msg.ID
+		return nil, nil // never acks
+	}, WithManualAck())
+	connectTestClient(t, client)
+
+	fake.handler(inboundPayload("m1", dispatchTypeA, ""))
+	<-runs
+	if err := <-errs; err.Kind != ErrMissingAck {
+		t.Fatalf("reported %v, want ErrMissingAck", err.Kind)
+	}
+	fake.handler(inboundPayload("m1", dispatchTypeA, "")) // redelivery is not a duplicate
+	select {
+	case <-runs:
+	case <-time.After(2 * time.Second):
+		t.Fatal("handler should run again for a message that was never acked")
+	}
+}
+
+// brokenSeenStore fails every operation.
+type brokenSeenStore struct{}
+
+func (brokenSeenStore) Add(string, []byte) error         { return errors.New("store down") }
+func (brokenSeenStore) Get(string) ([]byte, bool, error) { return nil, false, errors.New("store down") }
+
+func TestClient_Deduplication_StoreErrorProcessesMessage(t *testing.T) {
+	fake := &fakeTransport{}
+	errs := make(chan SDKError, 10)
+	client := newTestClient(t, fake, Config{Deduplication: &Deduplication{Store: brokenSeenStore{}}}, func(err SDKError) { errs <- err })
+	runs := make(chan string, 10)
+	client.Handle(dispatchTypeA, func(msg *Message) (*Message, error) {
+		runs <- msg.ID
+		return nil, nil
+	})
+	connectTestClient(t, client)
+
+	fake.handler(inboundPayload("m1", dispatchTypeA, ""))
+	<-runs
+	if err := <-errs; err.Kind != ErrDedupStore {
+		t.Errorf("reported %v, want ErrDedupStore", err.Kind)
+	}
+	if acks := fake.ackedIDs(); !slices.Equal(acks, []string{"m1"}) {
+		t.Errorf("acks = %v, want [m1]", acks)
+	}
+}
@@ -0,0 +1,362 @@
+package layr8
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+)
+
+// Backpressure selects what happens to an inbound message that arrives while
+// its handler (WithConcurrency) or the client (Config.MaxConcurrentHandlers)
+// is at its concurrency limit.
+type Backpressure int
+
+const (
+	// BackpressureQueue holds the message until a slot frees up. Queued
+	// messages start in arrival order and are acknowledged when they start. (default)
+	BackpressureQueue Backpressure = iota
+
+	// BackpressureReject acknowledges the message and replies to the sender
+	// with an "e.p.me.res" problem report.
+	BackpressureReject
+
+	// BackpressureWithholdAck drops the message without acknowledging it, so
+	// the cloud-node redelivers it later.
+	BackpressureWithholdAck
+)
+
+// Ordering selects which inbound messages a handler processes one at a time.
+// Messages that share a key are handled in arrival order; messages with
+// different keys still run in parallel.
+type Ordering int
+
+const (
+	// OrderNone handles every message independently. (default)
+	OrderNone Ordering = iota
+
+	// OrderByThread serializes messages that share a DIDComm thread ID.
+	// A message without a thread ID starts a new thread keyed by its own ID.
+	OrderByThread
+
+	// OrderBySender serializes messages from the same sender DID.
+	OrderBySender
+)
+
+// orderingKey returns the key msg is serialized under, or "" if entry is unordered.
+// Keys are shared across handlers, so a thread spanning several message
+// types is still processed in order.
+func orderingKey(entry handlerEntry, msg *Message) string {
+	switch entry.ordering {
+	case OrderByThread:
+		if msg.ThreadID != "" {
+			return "thid:" + msg.ThreadID
+		}
+		return "thid:" + msg.ID
+	case OrderBySender:
+		return "from:" + msg.From
+	default:
+		return ""
+	}
+}
+
+// HandlerStats reports concurrency and backpressure statistics for a handler.
+type HandlerStats struct {
+	Running  int // handler invocations currently executing
+	Queued   int // messages waiting for a free slot
+	Rejected int // messages rejected with a problem report
+	Withheld int // messages dropped without an ack for redelivery
+
+	Started   int           // handler invocations started
+	TotalWait time.Duration // total time started messages spent queued
+	MaxWait   time.Duration // longest time a started message spent queued
+}
+
+// defaultMaxQueued is the default for Config.MaxQueuedMessages.
+const defaultMaxQueued = 10000
+
+// dispatcher enforces per-handler and client-wide concurrency limits and
+// per-key ordering, and starts handlers without blocking the transport's
+// read loop.
+type dispatcher struct {
+	mu        sync.Mutex
+	limit     int // client-wide limit, 0 means unlimited
+	maxQueued int
+	running   int
+	queued    int    // messages waiting across all handlers
+	seq       uint64 // arrival order of queued messages
+	stats     map[string]*HandlerStats
+	ready     map[string]*runQueue    // handler → queued messages not held by their ordering key
+	keyed     map[string][]*queuedRun // ordering key → queued messages, oldest first
+	active    map[string]bool         // ordering keys with a running handler
+}
+
+type queuedRun struct {
+	entry    handlerEntry
+	msg      *Message
+	key      string
+	seq      uint64
+	enqueued time.Time
+}
+
+// runQueue is a heap of queued messages ordered by arrival. A message held
+// by its ordering key joins its handler's runQueue when the key frees up,
+// possibly behind later arrivals it must still precede.
+type runQueue []*queuedRun
+
+func (q runQueue) Len() int           { return len(q) }
+func (q runQueue) Less(i, j int) bool { return q[i].seq < q[j].seq }
+func (q runQueue) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+func (q *runQueue) Push(x any)        { *q = append(*q, x.(*queuedRun)) }
+func (q *runQueue) Pop() any {
+	old := *q
+	r := old[len(old)-1]
+	old[len(old)-1] = nil
+	*q = old[:len(old)-1]
+	return r
+}
+
+func newDispatcher(limit, maxQueued int) *dispatcher {
+	if maxQueued <= 0 {
+		maxQueued = defaultMaxQueued
+	}
+	return &dispatcher{
+		limit:     limit,
+		maxQueued: maxQueued,
+		stats:     make(map[string]*HandlerStats),
+		ready:     make(map[string]*runQueue),
+		keyed:     make(map[string][]*queuedRun),
+		active:    make(map[string]bool),
+	}
+}
+
+// held reports whether a message with key must wait behind an earlier one.
+// Must be called with d.mu held.
+func (d *dispatcher) held(key string) bool {
+	return key != "" && (d.active[key] || len(d.keyed[key]) > 0)
+}
+
+// enqueue queues q behind earlier messages with its ordering key, or makes
+// it ready to start. Must be called with d.mu held.
+func (d *dispatcher) enqueue(q *queuedRun) {
+	d.seq++
+	q.seq = d.seq
+	d.queued++
+	d.statsFor(q.entry.msgType).Queued++
+	if q.key != "" {
+		held := d.held(q.key)
+		d.keyed[q.key] = append(d.keyed[q.key], q)
+		if held {
+			return
+		}
+	}
+	d.makeReady(q)
+}
+
+// makeReady adds q to its handler's runQueue. Must be called with d.mu held.
+func (d *dispatcher) makeReady(q *queuedRun) {
+	rq, ok := d.ready[q.entry.msgType]
+	if !ok {
+		rq = &runQueue{}
+		d.ready[q.entry.msgType] = rq
+	}
+	heap.Push(rq, q)
+}
+
+// next removes and returns the earliest queued message whose handler has a
+// free slot, or nil. Must be called with d.mu held.
+func (d *dispatcher) next() *queuedRun {
+	var best *runQueue
+	for _, rq := range d.ready {
+		if rq.Len() == 0 || !d.canStart((*rq)[0].entry) {
+			continue
+		}
+		if best == nil || (*rq)[0].seq < (*best)[0].seq {
+			best = rq
+		}
+	}
+	if best == nil {
+		return nil
+	}
+	q := heap.Pop(best).(*queuedRun)
+	d.queued--
+	d.statsFor(q.entry.msgType).Queued--
+	if q.key != "" {
+		waiting := d.keyed[q.key]
+		waiting[0] = nil
+		if len(waiting) == 1 {
+			delete(d.keyed, q.key)
+		} else {
+			d.keyed[q.key] = waiting[1:]
+		}
+	}
+	return q
+}
+
+// drop discards every queued message and returns them. Must be called with
+// d.mu held.
+func (d *dispatcher) drop() []*queuedRun {
+	var dropped []*queuedRun
+	for _, rq := range d.ready {
+		for _, q := range *rq {
+			if q.key == "" {
+				dropped = append(dropped, q) // keyed ones are all in d.keyed
+			}
+		}
+	}
+	for _, waiting := range d.keyed {
+		dropped = append(dropped, waiting...)
+	}
+	clear(d.ready)
+	clear(d.keyed)
+	for _, s := range d.stats {
+		s.Queued = 0
+	}
+	d.queued = 0
+	return dropped
+}
+
+// statsFor returns the mutable stats for a handler. Must be called with d.mu held.
+func (d *dispatcher) statsFor(msgType string) *HandlerStats {
+	s, ok := d.stats[msgType]
+	if !ok {
+		s = &HandlerStats{}
+		d.stats[msgType] = s
+	}
+	return s
+}
+
+// canStart reports whether entry has a free slot. Must be called with d.mu held.
+func (d *dispatcher) canStart(entry handlerEntry) bool {
+	if d.limit > 0 && d.running >= d.limit {
+		return false
+	}
+	return entry.concurrency <= 0 || d.statsFor(entry.msgType).Running < entry.concurrency
+}
+
+// claim reserves a slot for entry and marks key as running. Must be called
+// with d.mu held.
+func (d *dispatcher) claim(entry handlerEntry, key string, wait time.Duration) {
+	if key != "" {
+		d.active[key] = true
+	}
+	s := d.statsFor(entry.msgType)
+	d.running++
+	s.Running++
+	s.Started++
+	s.TotalWait += wait
+	if wait > s.MaxWait {
+		s.MaxWait = wait
+	}
+}
+
+// dispatch starts the handler for msg, or applies the handler's backpressure
+// policy if no slot is free. A message whose ordering key is held by an
+// earlier message always waits in the queue behind it. A message that would
+// be queued while the queue is full is withheld instead.
+func (c *Client) dispatch(entry handlerEntry, msg *Message) {
+	d := c.dispatcher
+	key := orderingKey(entry, msg)
+	d.mu.Lock()
+	held := d.held(key)
+	if !held && d.canStart(entry) {
+		d.claim(entry, key, 0)
+		d.mu.Unlock()
+		c.startHandler(entry, msg, key)
+		return
+	}
+
+	policy := c.cfg.Backpressure
+	if entry.backpressure != nil {
+		policy = *entry.backpressure
+	}
+	if held {
+		policy = BackpressureQueue
+	}
+	if policy == BackpressureQueue && d.queued >= d.maxQueued {
+		policy = BackpressureWithholdAck
+	}
+	s := d.statsFor(entry.msgType)
+	switch policy {
+	case BackpressureReject:
+		s.Rejected++
+		d.mu.Unlock()
+		c.releaseClaim(msg)
+		c.ack(msg.ID)
+		c.replyProblem(msg, &ProblemReportError{
+			Code:    "e.p.me.res",
+			Comment: "agent is at its concurrency limit for {1}",
+			Args:    []string{msg.Type},
+		})
+	case BackpressureWithholdAck:
+		s.Withheld++
+		d.mu.Unlock()
+		c.releaseClaim(msg)
+	default:
+		d.enqueue(&queuedRun{entry: entry, msg: msg, key: key, enqueued: time.Now()})
+		d.mu.Unlock()
+	}
+}
+
+// startHandler acknowledges msg (unless manual ack) and runs the handler in
+// its own goroutine. The caller must have claimed a slot.
+func (c *Client) startHandler(entry handlerEntry, msg *Message, key string) {
+	msg.ack = c.newAckControl(entry, msg)
+
+	c.inflight.add()
+	go func() {
+		defer c.inflight.done()
+		defer c.finishHandler(entry, key)
+		defer c.releaseClaim(msg) // after runHandler recorded the outcome
+		c.runHandler(entry, msg)
+	}()
+}
+
+// finishHandler releases the slot and ordering key held by entry and starts
+// queued messages that can now run. While draining, queued messages are
+// dropped unacknowledged so the cloud-node redelivers them.
+func (c *Client) finishHandler(entry handlerEntry, key string) {
+	d := c.dispatcher
+	d.mu.Lock()
+	d.running--
+	d.statsFor(entry.msgType).Running--
+	if key != "" {
+		delete(d.active, key)
+		if waiting := d.keyed[key]; len(waiting) > 0 {
+			d.makeReady(waiting[0])
+		}
+	}
+
+	if c.State() == StateDraining {
+		dropped := d.drop()
+		d.mu.Unlock()
+		for _, q := range dropped {
+			c.releaseClaim(q.msg)
+		}
+		return
+	}
+
+	var ready []*queuedRun
+	for q := d.next(); q != nil; q = d.next() {
+		d.claim(q.entry, q.key, time.Since(q.enqueued))
+		ready = append(ready, q)
+	}
+	d.mu.Unlock()
+
+	for _, q := range ready {
+		c.startHandler(q.entry, q.msg, q.key)
+	}
+}
+
+// HandlerStats returns a snapshot of concurrency statistics keyed by the
+// handler's registration: its message type, its protocol for HandleProtocol,
+// or "*" for HandleDefault.
+func (c *Client) HandlerStats() map[string]HandlerStats {
+	d := c.dispatcher
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	out := make(map[string]HandlerStats, len(d.stats))
+	for msgType, s := range d.stats {
+		out[msgType] = *s
+	}
+	return out
+}
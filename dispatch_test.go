@@ -0,0 +1,336 @@
+package layr8
+
+import (
+	"encoding/json"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	dispatchTypeA = "https://layr8.io/protocols/jobs/1.0/a"
+	dispatchTypeB = "https://layr8.io/protocols/jobs/1.0/b"
+)
+
+// blockingHandler records started message IDs and blocks until released.
+type blockingHandler struct {
+	started chan string
+	release chan struct{}
+}
+
+func newBlockingHandler() *blockingHandler {
+	return &blockingHandler{started: make(chan string, 10), release: make(chan struct{}, 10)}
+}
+
+func (h *blockingHandler) fn(msg *Message) (*Message, error) {
+	h.started <- msg.ID
+	<-h.release
+	return nil, nil
+}
+
+func (h *blockingHandler) expectStart(t *testing.T, want string) {
+	t.Helper()
+	select {
+	case got := <-h.started:
+		if got != want {
+			t.Fatalf("started %q, want %q", got, want)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("timeout waiting for %q to start", want)
+	}
+}
+
+func (h *blockingHandler) expectNoStart(t *testing.T) {
+	t.Helper()
+	select {
+	case got := <-h.started:
+		t.Fatalf("started %q, want it held back", got)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestClient_WithConcurrency_QueuesInOrder(t *testing.T) {
+	fake := &fakeTransport{}
+	client := newTestClient(t, fake, Config{}, nil)
+	h := newBlockingHandler()
+	client.Handle(dispatchTypeA, h.fn, WithConcurrency(1))
+	connectTestClient(t, client)
+
+	for _, id := range []string{"m1", "m2", "m3"} {
+		fake.handler(inboundPayload(id, dispatchTypeA, "")) // must not block
+	}
+	h.expectStart(t, "m1")
+	h.expectNoStart(t)
+
+	stats := client.HandlerStats()[dispatchTypeA]
+	if stats.Running != 1 || stats.Queued != 2 {
+		t.Errorf("stats = %+v, want Running=1 Queued=2", stats)
+	}
+	// Queued messages are acknowledged only when they start.
+	if acks := fake.ackedIDs(); !slices.Equal(acks, []string{"m1"}) {
+		t.Errorf("acks = %v, want [m1]", acks)
+	}
+
+	h.release <- struct{}{}
+	h.expectStart(t, "m2")
+	h.release <- struct{}{}
+	h.expectStart(t, "m3")
+	h.release <- struct{}{}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for client.HandlerStats()[dispatchTypeA].Running > 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	stats = client.HandlerStats()[dispatchTypeA]
+	if stats.Started != 3 || stats.Queued != 0 || stats.MaxWait <= 0 {
+		t.Errorf("stats = %+v, want Started=3 Queued=0 MaxWait>0", stats)
+	}
+}
+
+func TestClient_WithBackpressure_Reject(t *testing.T) {
+	fake := &fakeTransport{}
+	client := newTestClient(t, fake, Config{}, nil)
+	h := newBlockingHandler()
+	client.Handle(dispatchTypeA, h.fn, WithConcurrency(1), WithBackpressure(BackpressureReject))
+	connectTestClient(t, client)
+	defer func() { h.release <- struct{}{} }()
+
+	fake.handler(inboundPayload("m1", dispatchTypeA, ""))
+	h.expectStart(t, "m1")
+	fake.handler(inboundPayload("m2", dispatchTypeA, ""))
+	h.expectNoStart(t)
+
+	if acks := fake.ackedIDs(); !slices.Contains(acks, "m2") {
+		t.Errorf("acks = %v, want rejected m2 acked", acks)
+	}
+	waitForSent(t, fake, 1)
+
+	fake.mu.Lock()
+	var report struct {
+		Type string             `json:"type"`
+		Thid string             `json:"thid"`
+		Body ProblemReportError `json:"body"`
+	}
+	json.Unmarshal(fake.sent[0], &report)
+	fake.mu.Unlock()
+	if report.Body.Code != "e.p.me.res" || report.Thid != "m2" {
+		t.Errorf("problem report = %+v, want code e.p.me.res on thread m2", report)
+	}
+	if got := client.HandlerStats()[dispatchTypeA].Rejected; got != 1 {
+		t.Errorf("Rejected = %d, want 1", got)
+	}
+}
+
+func TestClient_WithBackpressure_WithholdAck(t *testing.T) {
+	fake := &fakeTransport{}
+	client := newTestClient(t, fake, Config{Backpressure: BackpressureWithholdAck}, nil)
+	h := newBlockingHandler()
+	client.Handle(dispatchTypeA, h.fn, WithConcurrency(1))
+	connectTestClient(t, client)
+	defer func() { h.release <- struct{}{} }()
+
+	fake.handler(inboundPayload("m1", dispatchTypeA, ""))
+	h.expectStart(t, "m1")
+	fake.handler(inboundPayload("m2", dispatchTypeA, ""))
+	h.expectNoStart(t)
+
+	if acks := fake.ackedIDs(); slices.Contains(acks, "m2") {
+		t.Errorf("acks = %v, want m2 left unacknowledged", acks)
+	}
+	if n := len(fake.sentIDs()); n != 0 {
+		t.Errorf("sent %d messages, want none", n)
+	}
+	if got := client.HandlerStats()[dispatchTypeA].Withheld; got != 1 {
+		t.Errorf("Withheld = %d, want 1", got)
+	}
+}
+
+func TestClient_MaxQueuedMessages_WithholdsWhenFull(t *testing.T) {
+	fake := &fakeTransport{}
+	client := newTestClient(t, fake, Config{MaxQueuedMessages: 1}, nil)
+	h := newBlockingHandler()
+	client.Handle(dispatchTypeA, h.fn, WithConcurrency(1))
+	connectTestClient(t, client)
+
+	fake.handler(inboundPayload("m1", dispatchTypeA, ""))
+	h.expectStart(t, "m1")
+	fake.handler(inboundPayload("m2", dispatchTypeA, "")) // queued
+	fake.handler(inboundPayload("m3", dispatchTypeA, "")) // queue full
+
+	stats := client.HandlerStats()[dispatchTypeA]
+	if stats.Queued != 1 || stats.Withheld != 1 {
+		t.Errorf("stats = %+v, want Queued=1 Withheld=1", stats)
+	}
+	h.release <- struct{}{}
+	h.expectStart(t, "m2")
+	h.release <- struct{}{}
+	h.expectNoStart(t)
+	if acks := fake.ackedIDs(); slices.Contains(acks, "m3") {
+		t.Errorf("acks = %v, want m3 left unacknowledged", acks)
+	}
+}
+
+func TestClient_MaxConcurrentHandlers(t *testing.T) {
+	fake := &fakeTransport{}
+	client := newTestClient(t, fake, Config{MaxConcurrentHandlers: 1}, nil)
+	h := newBlockingHandler()
+	client.Handle(dispatchTypeA, h.fn)
+	client.Handle(dispatchTypeB, h.fn)
+	connectTestClient(t, client)
+
+	fake.handler(inboundPayload("a1", dispatchTypeA, ""))
+	fake.handler(inboundPayload("b1", dispatchTypeB, ""))
+	h.expectStart(t, "a1")
+	h.expectNoStart(t)
+
+	h.release <- struct{}{}
+	h.expectStart(t, "b1")
+	h.release <- struct{}{}
+}
+
+func TestHandlerRegistry_InvalidDispatchOptions(t *testing.T) {
+	noop := func(*Message) (*Message, error) { return nil, nil }
+	for _, opt := range []HandlerOption{WithConcurrency(-1), WithBackpressure(Backpressure(42)), WithOrdering(Ordering(42))} {
+		if err := newHandlerRegistry().add(scopeType, dispatchTypeA, ignoreContext(noop), []HandlerOption{opt}); err == nil {
+			t.Error("add() should reject an invalid dispatch option")
+		}
+	}
+}
+
+// gatedHandler blocks each message until its own gate is opened.
+type gatedHandler struct {
+	started chan string
+	mu      sync.Mutex
+	gates   map[string]chan struct{}
+}
+
+func newGatedHandler() *gatedHandler {
+	return &gatedHandler{started: make(chan string, 10), gates: make(map[string]chan struct{})}
+}
+
+func (h *gatedHandler) gate(id string) chan struct{} {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	g, ok := h.gates[id]
+	if !ok {
+		g = make(chan struct{})
+		h.gates[id] = g
+	}
+	return g
+}
+
+func (h *gatedHandler) fn(msg *Message) (*Message, error) {
+	h.started <- msg.ID
+	<-h.gate(msg.ID)
+	return nil, nil
+}
+
+func (h *gatedHandler) expectStarted(t *testing.T, want ...string) {
+	t.Helper()
+	var got []string
+	for range want {
+		select {
+		case id := <-h.started:
+			got = append(got, id)
+		case <-time.After(2 * time.Second):
+			t.Fatalf("started %v, want %v", got, want)
+		}
+	}
+	slices.Sort(got)
+	slices.Sort(want)
+	if !slices.Equal(got, want) {
+		t.Fatalf("started %v, want %v", got, want)
+	}
+	select {
+	case id := <-h.started:
+		t.Fatalf("started %q, want it held back", id)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestClient_WithOrdering_ByThread(t *testing.T) {
+	fake := &fakeTransport{}
+	client := newTestClient(t, fake, Config{}, nil)
+	h := newGatedHandler()
+	client.Handle(dispatchTypeA, h.fn, WithOrdering(OrderByThread))
+	client.Handle(dispatchTypeB, h.fn, WithOrdering(OrderByThread))
+	connectTestClient(t, client)
+
+	fake.handler(inboundPayload("t1-1", dispatchTypeA, "t1"))
+	fake.handler(inboundPayload("t2-1", dispatchTypeA, "t2"))
+	fake.handler(inboundPayload("t1-2", dispatchTypeB, "t1")) // same thread, other handler
+	fake.handler(inboundPayload("t1-3", dispatchTypeA, "t1"))
+
+	// Different threads run in parallel; later messages in t1 wait.
+	h.expectStarted(t, "t1-1", "t2-1")
+	if acks := fake.ackedIDs(); slices.Contains(acks, "t1-2") {
+		t.Errorf("acks = %v, want t1-2 acked only when it starts", acks)
+	}
+
+	close(h.gate("t1-1"))
+	h.expectStarted(t, "t1-2")
+	close(h.gate("t1-2"))
+	h.expectStarted(t, "t1-3")
+	close(h.gate("t1-3"))
+	close(h.gate("t2-1"))
+}
+
+func TestClient_WithOrdering_BySender(t *testing.T) {
+	fake := &fakeTransport{}
+	client := newTestClient(t, fake, Config{}, nil)
+	h := newGatedHandler()
+	client.Handle(dispatchTypeA, h.fn, WithOrdering(OrderBySender))
+	connectTestClient(t, client)
+
+	// inboundPayload messages all come from did:web:bob, whatever the thread.
+	fake.handler(inboundPayload("m1", dispatchTypeA, "t1"))
+	fake.handler(inboundPayload("m2", dispatchTypeA, "t2"))
+	h.expectStarted(t, "m1")
+
+	close(h.gate("m1"))
+	h.expectStarted(t, "m2")
+	close(h.gate("m2"))
+}
+
+func TestClient_WithOrdering_RespectsConcurrency(t *testing.T) {
+	fake := &fakeTransport{}
+	client := newTestClient(t, fake, Config{}, nil)
+	h := newGatedHandler()
+	client.Handle(dispatchTypeA, h.fn, WithOrdering(OrderByThread), WithConcurrency(1))
+	connectTestClient(t, client)
+
+	fake.handler(inboundPayload("t1-1", dispatchTypeA, "t1"))
+	fake.handler(inboundPayload("t2-1", dispatchTypeA, "t2"))
+	fake.handler(inboundPayload("t2-2", dispatchTypeA, "t2"))
+	h.expectStarted(t, "t1-1")
+
+	// t2-2 must not overtake t2-1, which is waiting for a free slot.
+	close(h.gate("t1-1"))
+	h.expectStarted(t, "t2-1")
+	close(h.gate("t2-1"))
+	h.expectStarted(t, "t2-2")
+	close(h.gate("t2-2"))
+}
+
+func TestClient_WithOrdering_ReleasedKeyKeepsArrivalOrder(t *testing.T) {
+	fake := &fakeTransport{}
+	client := newTestClient(t, fake, Config{}, nil)
+	h := newGatedHandler()
+	client.Handle(dispatchTypeA, h.fn, WithOrdering(OrderByThread), WithConcurrency(2))
+	connectTestClient(t, client)
+
+	fake.handler(inboundPayload("t1-1", dispatchTypeA, "t1"))
+	fake.handler(inboundPayload("t1-2", dispatchTypeA, "t1")) // held by t1-1
+	fake.handler(inboundPayload("t2-1", dispatchTypeA, "t2"))
+	fake.handler(inboundPayload("t3-1", dispatchTypeA, "t3")) // waits for a slot
+	h.expectStarted(t, "t1-1", "t2-1")
+
+	// t1-2 arrived before t3-1, so it takes the slot t1-1 frees.
+	close(h.gate("t1-1"))
+	h.expectStarted(t, "t1-2")
+	close(h.gate("t1-2"))
+	h.expectStarted(t, "t3-1")
+	close(h.gate("t2-1"))
+	close(h.gate("t3-1"))
+}
@@ -13,6 +13,11 @@ var (
 	ErrNotConnected     = errors.New("client is not connected")
 	ErrAlreadyConnected = errors.New("client is already connected")
 	ErrClientClosed     = errors.New("client is closed")
+	ErrReconnectGaveUp  = errors.New("reconnect gave up")
+	// ErrConnectionLost is returned by Transport.Send when the connection
+	// drops after the message was written but before the server replied.
+	// The message may or may not have reached the node.
+	ErrConnectionLost = errors.New("connection lost before the server replied")
 )
 
 // ProblemReportError represents a DIDComm problem report received from a remote agent.
@@ -43,6 +48,22 @@ func (e *ConnectionError) Error() string {
 	return fmt.Sprintf("connection error [%s]: %s", e.URL, e.Reason)
 }
 
+// RequestError is returned by Request when it was called with WithRetry.
+// It wraps the error of the last attempt.
+type RequestError struct {
+	MessageID string
+	Attempts  int // number of times the request was sent or tried
+	Err       error
+}
+
+func (e *RequestError) Error() string {
+	return fmt.Sprintf("request %s failed after %d attempts: %v", e.MessageID, e.Attempts, e.Err)
+}
+
+func (e *RequestError) Unwrap() error {
+	return e.Err
+}
+
 // ErrorKind classifies SDK-level errors that cannot be returned to a caller.
 type ErrorKind int
 
@@ -52,6 +73,12 @@ const (
 	ErrHandlerPanic                    // handler goroutine panicked
 	ErrServerReject                    // server refused a sent message (authz, routing, etc.)
 	ErrTransportWrite                  // failed to write to connection
+	ErrQueueOverflow                   // outbound queue full, message dropped
+	ErrQueueExpired                    // queued outbound message exceeded its max age
+	ErrDeadLetter                      // dead-letter sink failed to store a message
+	ErrMaxDeliveries                   // message dead-lettered after repeated handler failures
+	ErrMissingAck                      // manual-ack handler returned without settling its message
+	ErrDedupStore                      // deduplication store failed to look up or record a message ID
 )
 
 var errorKindNames = [...]string{
@@ -60,6 +87,12 @@ var errorKindNames = [...]string{
 	ErrHandlerPanic:   "ErrHandlerPanic",
 	ErrServerReject:   "ErrServerReject",
 	ErrTransportWrite: "ErrTransportWrite",
+	ErrQueueOverflow:  "ErrQueueOverflow",
+	ErrQueueExpired:   "ErrQueueExpired",
+	ErrDeadLetter:     "ErrDeadLetter",
+	ErrMaxDeliveries:  "ErrMaxDeliveries",
+	ErrMissingAck:     "ErrMissingAck",
+	ErrDedupStore:     "ErrDedupStore",
 }
 
 func (k ErrorKind) String() string {
@@ -122,6 +122,8 @@ func TestErrorKind_String(t *testing.T) {
 		{ErrHandlerPanic, "ErrHandlerPanic"},
 		{ErrServerReject, "ErrServerReject"},
 		{ErrTransportWrite, "ErrTransportWrite"},
+		{ErrQueueOverflow, "ErrQueueOverflow"},
+		{ErrQueueExpired, "ErrQueueExpired"},
 	}
 	for _, tt := range tests {
 		if got := tt.kind.String(); got != tt.want {
@@ -2,7 +2,8 @@
 //
 // Demonstrates manual ack: messages are only acknowledged after they
 // are safely written to disk. If the process crashes between receive
-// and ack, the cloud-node redelivers the message.
+// and ack, the cloud-node redelivers the message. A failed write is
+// retried after a delay with Nack; a body that isn't JSON is rejected.
 //
 // Messages are appended as JSON lines to messages.jsonl.
 //
@@ -18,6 +19,7 @@ import (
 	"os"
 	"os/signal"
 	"sync"
+	"time"
 
 	layr8 "github.com/layr8/go-sdk"
 )
@@ -49,7 +51,10 @@ func main() {
 	client.Handle("https://layr8.io/protocols/order/1.0/created",
 		func(msg *layr8.Message) (*layr8.Message, error) {
 			var body any
-			_ = msg.UnmarshalBody(&body)
+			if err := msg.UnmarshalBody(&body); err != nil {
+				msg.Reject("body must be JSON") // acked; sender gets a problem report
+				return nil, nil
+			}
 
 			line, err := json.Marshal(record{
 				ID:   msg.ID,
@@ -63,7 +68,7 @@ func main() {
 			line = append(line, '\n')
 
 			// Persist first — if this fails, the message is NOT acked
-			// and the cloud-node will redeliver it.
+			// and is retried after a delay.
 			mu.Lock()
 			_, err = f.Write(line)
 			if err == nil {
@@ -71,7 +76,9 @@ func main() {
 			}
 			mu.Unlock()
 			if err != nil {
-				return nil, err // not acked — will be redelivered
+				log.Printf("persist %s: %v; retrying", msg.ID, err)
+				msg.Nack(5 * time.Second) // not acked — handled again later
+				return nil, nil
 			}
 
 			msg.Ack() // safe to ack now
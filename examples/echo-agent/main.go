@@ -72,18 +72,11 @@ func runAgent(ctx context.Context) error {
 		return fmt.Errorf("NewClient: %w", err)
 	}
 
-	client.Handle(echoRequestType, func(msg *layr8.Message) (*layr8.Message, error) {
-		var req EchoRequest
-		if err := msg.UnmarshalBody(&req); err != nil {
-			return nil, err
-		}
-		log.Printf("echo request from %s: %q", msg.From, req.Message)
-
-		return &layr8.Message{
-			Type: echoResponseType,
-			Body: EchoResponse{Echo: req.Message},
-		}, nil
-	})
+	layr8.HandleTyped(client, echoRequestType, echoResponseType,
+		func(ctx context.Context, msg *layr8.Message, req EchoRequest) (EchoResponse, error) {
+			log.Printf("echo request from %s: %q", msg.From, req.Message)
+			return EchoResponse{Echo: req.Message}, nil
+		})
 
 	disconnected := make(chan error, 1)
 	client.OnDisconnect(func(err error) {
@@ -171,11 +164,7 @@ func pingLoop(ctx context.Context, client *layr8.Client, peerDID string) {
 
 			start := time.Now()
 			reqCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
-			resp, err := client.Request(reqCtx, &layr8.Message{
-				Type: echoRequestType,
-				To:   []string{peerDID},
-				Body: EchoRequest{Message: msg},
-			})
+			echo, err := layr8.RequestTyped[EchoRequest, EchoResponse](reqCtx, client, echoRequestType, peerDID, EchoRequest{Message: msg})
 			cancel()
 			rtt := time.Since(start)
 
@@ -183,12 +172,6 @@ func pingLoop(ctx context.Context, client *layr8.Client, peerDID string) {
 				log.Printf("[→ %s] ping #%d failed (%s): %v", shortDID(peerDID), seq, rtt.Round(time.Millisecond), err)
 				continue
 			}
-
-			var echo EchoResponse
-			if err := resp.UnmarshalBody(&echo); err != nil {
-				log.Printf("[→ %s] ping #%d bad response: %v", shortDID(peerDID), seq, err)
-				continue
-			}
 			log.Printf("[→ %s] ping #%d reply (%s): %q", shortDID(peerDID), seq, rtt.Round(time.Millisecond), echo.Echo)
 		}
 	}
@@ -5,7 +5,7 @@
 // DIDComm envelope construction, heartbeats, reconnection, and thread correlation.
 // With the SDK, the agent focuses purely on its domain logic.
 //
-// Demonstrates: Handle (request/response), Request (outbound), manual ack,
+// Demonstrates: HandleTyped (request/response), Request (outbound), manual ack,
 // concurrent fan-out, MessageContext for authorization.
 package main
 
@@ -66,28 +66,20 @@ func main() {
 
 	// Handle incoming query requests with manual ack
 	// We only ack after successful execution to enable redelivery on failure.
-	client.Handle(ProtocolQueryType,
-		func(msg *layr8.Message) (*layr8.Message, error) {
-			var req QueryRequest
-			if err := msg.UnmarshalBody(&req); err != nil {
-				return nil, fmt.Errorf("invalid query request: %w", err)
-			}
-
+	// Bodies that don't decode into QueryRequest get a problem report automatically.
+	layr8.HandleTyped(client, ProtocolQueryType, ProtocolResultType,
+		func(ctx context.Context, msg *layr8.Message, req QueryRequest) (*QueryResponse, error) {
 			log.Printf("query from %s: %s (action=%s)", msg.From, req.Query, req.Action)
 
 			// Execute query
-			resp, err := executeQuery(db, req)
+			resp, err := executeQuery(ctx, db, req)
 			if err != nil {
 				return nil, fmt.Errorf("query execution failed: %w", err)
 			}
 
 			// Ack after successful execution
 			msg.Ack()
-
-			return &layr8.Message{
-				Type: ProtocolResultType,
-				Body: resp,
-			}, nil
+			return resp, nil
 		},
 		layr8.WithManualAck(),
 	)
@@ -104,14 +96,14 @@ func main() {
 	<-ctx.Done()
 }
 
-func executeQuery(db *sql.DB, req QueryRequest) (*QueryResponse, error) {
+func executeQuery(ctx context.Context, db *sql.DB, req QueryRequest) (*QueryResponse, error) {
 	action := strings.ToUpper(strings.Fields(req.Query)[0])
 
 	switch action {
 	case "SELECT":
-		return executeSelect(db, req)
+		return executeSelect(ctx, db, req)
 	case "INSERT", "UPDATE", "DELETE":
-		return executeExec(db, req)
+		return executeExec(ctx, db, req)
 	default:
 		return &QueryResponse{
 			Decision: "deny",
@@ -120,8 +112,8 @@ func executeQuery(db *sql.DB, req QueryRequest) (*QueryResponse, error) {
 	}
 }
 
-func executeSelect(db *sql.DB, req QueryRequest) (*QueryResponse, error) {
-	rows, err := db.Query(req.Query)
+func executeSelect(ctx context.Context, db *sql.DB, req QueryRequest) (*QueryResponse, error) {
+	rows, err := db.QueryContext(ctx, req.Query)
 	if err != nil {
 		return nil, err
 	}
@@ -152,8 +144,8 @@ func executeSelect(db *sql.DB, req QueryRequest) (*QueryResponse, error) {
 	}, nil
 }
 
-func executeExec(db *sql.DB, req QueryRequest) (*QueryResponse, error) {
-	_, err := db.Exec(req.Query)
+func executeExec(ctx context.Context, db *sql.DB, req QueryRequest) (*QueryResponse, error) {
+	_, err := db.ExecContext(ctx, req.Query)
 	if err != nil {
 		return nil, err
 	}
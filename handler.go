@@ -1,9 +1,14 @@
 package layr8
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 )
 
 // HandlerFunc is the signature for message handlers.
@@ -11,47 +16,239 @@ import (
 // or (nil, nil) for fire-and-forget inbound messages.
 type HandlerFunc func(msg *Message) (*Message, error)
 
+// HandlerFuncCtx is a HandlerFunc that also receives a context. The context
+// is cancelled when the client is closed, carries the deadline set by
+// WithTimeout, and holds the inbound message (see MessageFromContext).
+type HandlerFuncCtx func(ctx context.Context, msg *Message) (*Message, error)
+
+type messageContextKey struct{}
+
+// MessageFromContext returns the inbound message being handled, if ctx was
+// passed to a HandlerFuncCtx (or derived from such a context).
+func MessageFromContext(ctx context.Context) (*Message, bool) {
+	msg, ok := ctx.Value(messageContextKey{}).(*Message)
+	return msg, ok
+}
+
+// handlerScope says how a handler's key is matched against a message type.
+type handlerScope int
+
+const (
+	scopeType     handlerScope = iota // key is an exact message type
+	scopeProtocol                     // key is a protocol base URI, or a major-version range ending in ".x"
+	scopeDefault                      // catch-all; key is defaultHandlerKey
+)
+
+// defaultHandlerKey identifies the HandleDefault handler, e.g. in HandlerStats.
+const defaultHandlerKey = "*"
+
 type handlerEntry struct {
-	fn        HandlerFunc
-	manualAck bool
+	msgType      string // registration key: message type, protocol, or defaultHandlerKey
+	scope        handlerScope
+	fn           HandlerFuncCtx
+	manualAck    bool
+	concurrency  int           // 0 means unlimited
+	backpressure *Backpressure // nil means Config.Backpressure
+	ordering     Ordering
+	timeout      time.Duration // 0 means no deadline
+	middleware   []Middleware  // wraps fn, inside the client-wide middleware
+	minors       []int         // minor versions a ".x" range is registered for; nil means 0 only
+}
+
+// advertised returns the protocols the entry is registered for with the
+// cloud-node. A ".x" range stands for each of its minor versions.
+func (e handlerEntry) advertised() []string {
+	switch e.scope {
+	case scopeType:
+		return []string{deriveProtocol(e.msgType)}
+	case scopeProtocol:
+		name, major, minor, _ := splitProtocol(e.msgType)
+		if minor != "x" {
+			return []string{e.msgType}
+		}
+		minors := e.minors
+		if minors == nil {
+			minors = []int{0}
+		}
+		versions := make([]string, len(minors))
+		for i, m := range minors {
+			versions[i] = name + "/" + major + "." + strconv.Itoa(m)
+		}
+		return versions
+	default:
+		return nil
+	}
 }
 
 type handlerRegistry struct {
-	mu       sync.RWMutex
-	handlers map[string]handlerEntry // message type → handler
+	mu         sync.RWMutex
+	handlers   map[string]handlerEntry // message type → handler
+	byProtocol map[string]handlerEntry // protocol base URI or ".x" range → handler
+	fallback   *handlerEntry           // HandleDefault handler, if any
 }
 
 func newHandlerRegistry() *handlerRegistry {
 	return &handlerRegistry{
-		handlers: make(map[string]handlerEntry),
+		handlers:   make(map[string]handlerEntry),
+		byProtocol: make(map[string]handlerEntry),
 	}
 }
 
-func (r *handlerRegistry) register(msgType string, fn HandlerFunc, opts ...HandlerOption) error {
+// ignoreContext adapts a HandlerFunc to a HandlerFuncCtx.
+func ignoreContext(fn HandlerFunc) HandlerFuncCtx {
+	return func(_ context.Context, msg *Message) (*Message, error) {
+		return fn(msg)
+	}
+}
+
+// add registers fn under key. Protocol keys must already be normalized
+// (see normalizeProtocol).
+func (r *handlerRegistry) add(scope handlerScope, key string, fn HandlerFuncCtx, opts []HandlerOption) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	if _, exists := r.handlers[msgType]; exists {
-		return fmt.Errorf("handler already registered for message type %q", msgType)
+	if _, exists := r.get(scope, key); exists {
+		switch scope {
+		case scopeProtocol:
+			return fmt.Errorf("handler already registered for protocol %q", key)
+		case scopeDefault:
+			return errors.New("default handler already registered")
+		default:
+			return fmt.Errorf("handler already registered for message type %q", key)
+		}
 	}
 
 	o := handlerDefaults()
 	for _, opt := range opts {
 		opt(&o)
 	}
+	if o.concurrency < 0 {
+		return fmt.Errorf("WithConcurrency: limit must not be negative, got %d", o.concurrency)
+	}
+	if o.backpressure != nil && (*o.backpressure < BackpressureQueue || *o.backpressure > BackpressureWithholdAck) {
+		return fmt.Errorf("WithBackpressure: unknown value %d", *o.backpressure)
+	}
+	if o.ordering < OrderNone || o.ordering > OrderBySender {
+		return fmt.Errorf("WithOrdering: unknown value %d", o.ordering)
+	}
+	if o.timeout < 0 {
+		return fmt.Errorf("WithTimeout: timeout must not be negative, got %s", o.timeout)
+	}
+	if o.minors != nil {
+		if _, _, minor, _ := splitProtocol(key); scope != scopeProtocol || minor != "x" {
+			return errors.New("WithMinorVersions: only applies to a major-version range such as 1.x")
+		}
+		if len(o.minors) == 0 {
+			return errors.New("WithMinorVersions: at least one minor version is required")
+		}
+		if slices.Min(o.minors) < 0 {
+			return fmt.Errorf("WithMinorVersions: minor versions must not be negative, got %v", o.minors)
+		}
+	}
 
-	r.handlers[msgType] = handlerEntry{
-		fn:        fn,
-		manualAck: o.manualAck,
+	entry := handlerEntry{
+		msgType:      key,
+		scope:        scope,
+		fn:           fn,
+		manualAck:    o.manualAck,
+		concurrency:  o.concurrency,
+		backpressure: o.backpressure,
+		ordering:     o.ordering,
+		timeout:      o.timeout,
+		middleware:   o.middleware,
+		minors:       o.minors,
+	}
+	// A protocol version belongs to one protocol handler, so that a range
+	// receives every minor version it registers with the cloud-node.
+	if scope == scopeProtocol {
+		for _, other := range r.byProtocol {
+			for _, version := range entry.advertised() {
+				if slices.Contains(other.advertised(), version) {
+					return fmt.Errorf("protocol %q is already handled by the handler for %q", version, other.msgType)
+				}
+			}
+		}
 	}
+	r.put(entry)
 	return nil
 }
 
+// get returns the entry registered under key. Must be called with r.mu held.
+func (r *handlerRegistry) get(scope handlerScope, key string) (handlerEntry, bool) {
+	switch scope {
+	case scopeProtocol:
+		entry, ok := r.byProtocol[key]
+		return entry, ok
+	case scopeDefault:
+		if r.fallback == nil {
+			return handlerEntry{}, false
+		}
+		return *r.fallback, true
+	default:
+		entry, ok := r.handlers[key]
+		return entry, ok
+	}
+}
+
+// put stores entry under its key. Must be called with r.mu held.
+func (r *handlerRegistry) put(entry handlerEntry) {
+	switch entry.scope {
+	case scopeProtocol:
+		r.byProtocol[entry.msgType] = entry
+	case scopeDefault:
+		r.fallback = &entry
+	default:
+		r.handlers[entry.msgType] = entry
+	}
+}
+
+// unregister removes and returns the entry registered under key.
+func (r *handlerRegistry) unregister(scope handlerScope, key string) (handlerEntry, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	entry, ok := r.get(scope, key)
+	switch scope {
+	case scopeProtocol:
+		delete(r.byProtocol, key)
+	case scopeDefault:
+		r.fallback = nil
+	default:
+		delete(r.handlers, key)
+	}
+	return entry, ok
+}
+
+// restore puts back an entry removed by unregister.
+func (r *handlerRegistry) restore(entry handlerEntry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.put(entry)
+}
+
+// lookup finds the handler for msgType. The most specific registration wins:
+// the exact message type, then its protocol version (".../echo/1.0"), then
+// its major-version range (".../echo/1.x"), then the default handler. A range
+// never shares a registered minor version with a protocol version (see add).
 func (r *handlerRegistry) lookup(msgType string) (handlerEntry, bool) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
-	entry, ok := r.handlers[msgType]
-	return entry, ok
+
+	if entry, ok := r.handlers[msgType]; ok {
+		return entry, true
+	}
+	proto := deriveProtocol(msgType)
+	if entry, ok := r.byProtocol[proto]; ok {
+		return entry, true
+	}
+	if name, major, _, ok := splitProtocol(proto); ok {
+		if entry, ok := r.byProtocol[name+"/"+major+".x"]; ok {
+			return entry, true
+		}
+	}
+	if r.fallback != nil {
+		return *r.fallback, true
+	}
+	return handlerEntry{}, false
 }
 
 // protocols returns the unique protocol base URIs derived from registered handler message types.
@@ -70,16 +267,74 @@ func (r *handlerRegistry) protocols() []string {
 	seen[problemReportProtocol] = struct{}{}
 	protocols = append(protocols, problemReportProtocol)
 
-	for msgType := range r.handlers {
-		proto := deriveProtocol(msgType)
+	add := func(proto string) {
 		if _, ok := seen[proto]; !ok {
 			seen[proto] = struct{}{}
 			protocols = append(protocols, proto)
 		}
 	}
+	for _, entry := range r.handlers {
+		for _, proto := range entry.advertised() {
+			add(proto)
+		}
+	}
+	for _, entry := range r.byProtocol {
+		for _, proto := range entry.advertised() {
+			add(proto)
+		}
+	}
 	return protocols
 }
 
+// sameProtocols reports whether a and b hold the same protocols in any order.
+func sameProtocols(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	a, b = slices.Clone(a), slices.Clone(b)
+	slices.Sort(a)
+	slices.Sort(b)
+	return slices.Equal(a, b)
+}
+
+// normalizeProtocol validates a protocol passed to HandleProtocol and strips
+// any trailing slash. The last path segment must be a version such as "1.0"
+// or a major-version range such as "1.x".
+func normalizeProtocol(protocol string) (string, error) {
+	protocol = strings.TrimSuffix(protocol, "/")
+	if _, _, _, ok := splitProtocol(protocol); !ok {
+		return "", fmt.Errorf("protocol %q must end in a version such as 1.0 or 1.x", protocol)
+	}
+	return protocol, nil
+}
+
+// splitProtocol splits a protocol base URI into its name and semver version:
+// "https://layr8.io/protocols/echo/1.0" → ("https://layr8.io/protocols/echo", "1", "0").
+// minor is "x" for a major-version range.
+func splitProtocol(protocol string) (name, major, minor string, ok bool) {
+	idx := strings.LastIndex(protocol, "/")
+	if idx <= 0 {
+		return "", "", "", false
+	}
+	major, minor, found := strings.Cut(protocol[idx+1:], ".")
+	if !found || !isDigits(major) || (minor != "x" && !isDigits(minor)) {
+		return "", "", "", false
+	}
+	return protocol[:idx], major, minor, true
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
 // deriveProtocol extracts the protocol base URI by removing the last path segment.
 // "https://layr8.io/protocols/echo/1.0/request" → "https://layr8.io/protocols/echo/1.0"
 func deriveProtocol(msgType string) string {
@@ -1,16 +1,19 @@
 package layr8
 
 import (
+	"context"
+	"slices"
 	"testing"
+	"time"
 )
 
 func TestHandlerRegistry_Register(t *testing.T) {
 	r := newHandlerRegistry()
 	handler := func(msg *Message) (*Message, error) { return nil, nil }
 
-	err := r.register("https://layr8.io/protocols/echo/1.0/request", handler)
+	err := r.add(scopeType, "https://layr8.io/protocols/echo/1.0/request", ignoreContext(handler), nil)
 	if err != nil {
-		t.Fatalf("register() error: %v", err)
+		t.Fatalf("add() error: %v", err)
 	}
 
 	entry, ok := r.lookup("https://layr8.io/protocols/echo/1.0/request")
@@ -26,7 +29,7 @@ func TestHandlerRegistry_RegisterWithManualAck(t *testing.T) {
 	r := newHandlerRegistry()
 	handler := func(msg *Message) (*Message, error) { return nil, nil }
 
-	r.register("https://layr8.io/protocols/echo/1.0/request", handler, WithManualAck())
+	r.add(scopeType, "https://layr8.io/protocols/echo/1.0/request", ignoreContext(handler), []HandlerOption{WithManualAck()})
 
 	entry, _ := r.lookup("https://layr8.io/protocols/echo/1.0/request")
 	if !entry.manualAck {
@@ -38,10 +41,10 @@ func TestHandlerRegistry_DuplicateRegistration(t *testing.T) {
 	r := newHandlerRegistry()
 	handler := func(msg *Message) (*Message, error) { return nil, nil }
 
-	r.register("https://layr8.io/protocols/echo/1.0/request", handler)
-	err := r.register("https://layr8.io/protocols/echo/1.0/request", handler)
+	r.add(scopeType, "https://layr8.io/protocols/echo/1.0/request", ignoreContext(handler), nil)
+	err := r.add(scopeType, "https://layr8.io/protocols/echo/1.0/request", ignoreContext(handler), nil)
 	if err == nil {
-		t.Fatal("register() should error on duplicate message type")
+		t.Fatal("add() should error on duplicate message type")
 	}
 }
 
@@ -57,9 +60,9 @@ func TestHandlerRegistry_DeriveProtocols(t *testing.T) {
 	r := newHandlerRegistry()
 	handler := func(msg *Message) (*Message, error) { return nil, nil }
 
-	r.register("https://layr8.io/protocols/echo/1.0/request", handler)
-	r.register("https://layr8.io/protocols/echo/1.0/response", handler)
-	r.register("https://layr8.io/protocols/postgres/1.0/query", handler)
+	r.add(scopeType, "https://layr8.io/protocols/echo/1.0/request", ignoreContext(handler), nil)
+	r.add(scopeType, "https://layr8.io/protocols/echo/1.0/response", ignoreContext(handler), nil)
+	r.add(scopeType, "https://layr8.io/protocols/postgres/1.0/query", ignoreContext(handler), nil)
 
 	protocols := r.protocols()
 
@@ -90,8 +93,8 @@ func TestHandlerRegistry_DeriveProtocols_DIDComm(t *testing.T) {
 	r := newHandlerRegistry()
 	handler := func(msg *Message) (*Message, error) { return nil, nil }
 
-	r.register("https://didcomm.org/basicmessage/2.0/message", handler)
-	r.register("https://didcomm.org/report-problem/2.0/problem-report", handler)
+	r.add(scopeType, "https://didcomm.org/basicmessage/2.0/message", ignoreContext(handler), nil)
+	r.add(scopeType, "https://didcomm.org/report-problem/2.0/problem-report", ignoreContext(handler), nil)
 
 	protocols := r.protocols()
 	if len(protocols) != 2 {
@@ -141,3 +144,128 @@ func TestDeriveProtocol(t *testing.T) {
 		}
 	}
 }
+
+func TestHandlerRegistry_RegisterContextWithTimeout(t *testing.T) {
+	r := newHandlerRegistry()
+	handler := func(ctx context.Context, msg *Message) (*Message, error) { return nil, nil }
+
+	if err := r.add(scopeType, "https://layr8.io/protocols/echo/1.0/request", handler, []HandlerOption{WithTimeout(time.Second)}); err != nil {
+		t.Fatalf("add() error: %v", err)
+	}
+	entry, _ := r.lookup("https://layr8.io/protocols/echo/1.0/request")
+	if entry.timeout != time.Second {
+		t.Errorf("timeout = %s, want 1s", entry.timeout)
+	}
+
+	if err := r.add(scopeType, "https://layr8.io/protocols/echo/1.0/response", handler, []HandlerOption{WithTimeout(-time.Second)}); err == nil {
+		t.Error("add() should reject a negative timeout")
+	}
+}
+
+func TestMessageFromContext_Missing(t *testing.T) {
+	if _, ok := MessageFromContext(context.Background()); ok {
+		t.Error("MessageFromContext() should report false for a plain context")
+	}
+}
+
+func TestHandlerRegistry_LookupPrecedence(t *testing.T) {
+	r := newHandlerRegistry()
+	noop := func(ctx context.Context, msg *Message) (*Message, error) { return nil, nil }
+	r.add(scopeType, "https://layr8.io/protocols/echo/1.0/request", noop, nil)
+	r.add(scopeProtocol, "https://layr8.io/protocols/echo/1.0", noop, nil)
+	r.add(scopeProtocol, "https://layr8.io/protocols/echo/1.x", noop, []HandlerOption{WithMinorVersions(1, 2)})
+	r.add(scopeDefault, defaultHandlerKey, noop, nil)
+
+	tests := []struct {
+		msgType string
+		want    string
+	}{
+		{"https://layr8.io/protocols/echo/1.0/request", "https://layr8.io/protocols/echo/1.0/request"},
+		{"https://layr8.io/protocols/echo/1.0/response", "https://layr8.io/protocols/echo/1.0"},
+		{"https://layr8.io/protocols/echo/1.2/request", "https://layr8.io/protocols/echo/1.x"},
+		{"https://layr8.io/protocols/echo/2.0/request", defaultHandlerKey},
+		{"https://didcomm.org/basicmessage/2.0/message", defaultHandlerKey},
+	}
+	for _, tt := range tests {
+		entry, ok := r.lookup(tt.msgType)
+		if !ok || entry.msgType != tt.want {
+			t.Errorf("lookup(%q) = %q, %v; want %q", tt.msgType, entry.msgType, ok, tt.want)
+		}
+	}
+
+	r.unregister(scopeDefault, defaultHandlerKey)
+	if _, ok := r.lookup("https://didcomm.org/basicmessage/2.0/message"); ok {
+		t.Error("lookup() should miss once the default handler is removed")
+	}
+}
+
+func TestHandlerRegistry_ProtocolHandlersAdvertised(t *testing.T) {
+	r := newHandlerRegistry()
+	noop := func(ctx context.Context, msg *Message) (*Message, error) { return nil, nil }
+	r.add(scopeProtocol, "https://layr8.io/protocols/echo/1.x", noop, nil)
+	r.add(scopeProtocol, "https://layr8.io/protocols/jobs/2.x", noop, []HandlerOption{WithMinorVersions(0, 3)})
+	r.add(scopeProtocol, "https://layr8.io/protocols/chat/2.1", noop, nil)
+	r.add(scopeDefault, defaultHandlerKey, noop, nil)
+
+	got := r.protocols()
+	slices.Sort(got)
+	want := []string{
+		"https://didcomm.org/report-problem/2.0",
+		"https://layr8.io/protocols/chat/2.1",
+		"https://layr8.io/protocols/echo/1.0",
+		"https://layr8.io/protocols/jobs/2.0",
+		"https://layr8.io/protocols/jobs/2.3",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("protocols() = %v, want %v", got, want)
+	}
+}
+
+func TestHandlerRegistry_RangeOwnsItsMinorVersions(t *testing.T) {
+	r := newHandlerRegistry()
+	noop := func(ctx context.Context, msg *Message) (*Message, error) { return nil, nil }
+	if err := r.add(scopeProtocol, "https://layr8.io/protocols/echo/1.x", noop, []HandlerOption{WithMinorVersions(0, 3)}); err != nil {
+		t.Fatalf("add(1.x) error: %v", err)
+	}
+	if err := r.add(scopeProtocol, "https://layr8.io/protocols/echo/1.3", noop, nil); err == nil {
+		t.Error("add(1.3) should fail while the 1.x range registers minor version 3")
+	}
+	if err := r.add(scopeProtocol, "https://layr8.io/protocols/echo/1.2", noop, nil); err != nil {
+		t.Errorf("add(1.2) error: %v", err)
+	}
+	if entry, _ := r.lookup("https://layr8.io/protocols/echo/1.3/request"); entry.msgType != "https://layr8.io/protocols/echo/1.x" {
+		t.Errorf("lookup(1.3) = %q, want the 1.x range", entry.msgType)
+	}
+
+	for _, opts := range [][]HandlerOption{
+		{WithMinorVersions()},
+		{WithMinorVersions(-1)},
+	} {
+		if err := r.add(scopeProtocol, "https://layr8.io/protocols/chat/1.x", noop, opts); err == nil {
+			t.Error("add() with invalid minor versions should fail")
+		}
+	}
+	if err := r.add(scopeProtocol, "https://layr8.io/protocols/chat/1.0", noop, []HandlerOption{WithMinorVersions(1)}); err == nil {
+		t.Error("WithMinorVersions on a specific version should fail")
+	}
+}
+
+func TestNormalizeProtocol(t *testing.T) {
+	tests := []struct {
+		in, want string
+		wantErr  bool
+	}{
+		{"https://layr8.io/protocols/echo/1.0", "https://layr8.io/protocols/echo/1.0", false},
+		{"https://layr8.io/protocols/echo/1.0/", "https://layr8.io/protocols/echo/1.0", false},
+		{"https://layr8.io/protocols/echo/1.x", "https://layr8.io/protocols/echo/1.x", false},
+		{"https://layr8.io/protocols/echo", "", true},
+		{"https://layr8.io/protocols/echo/x.1", "", true},
+		{"1.0", "", true},
+	}
+	for _, tt := range tests {
+		got, err := normalizeProtocol(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("normalizeProtocol(%q) = %q, %v; want %q, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package layr8
+
+import (
+	"fmt"
+	"time"
+)
+
+// HeartbeatPolicy controls how connection liveness is checked.
+// The zero value sends a heartbeat every 30s and treats the connection as
+// dead after 2 consecutive heartbeats go unanswered.
+type HeartbeatPolicy struct {
+	// Interval between heartbeats. Default: 30s.
+	Interval time.Duration
+
+	// MaxMissed is the number of consecutive unanswered heartbeats after which
+	// the connection is force-closed and the reconnect policy takes over. Default: 2.
+	MaxMissed int
+
+	// OnLatency is called with the round-trip time of every answered heartbeat.
+	// Use it to export a connection-health metric. It must not block.
+	OnLatency func(rtt time.Duration)
+}
+
+func (p HeartbeatPolicy) withDefaults() HeartbeatPolicy {
+	if p.Interval == 0 {
+		p.Interval = 30 * time.Second
+	}
+	if p.MaxMissed == 0 {
+		p.MaxMissed = 2
+	}
+	return p
+}
+
+func (p HeartbeatPolicy) validate() error {
+	if p.Interval < 0 {
+		return fmt.Errorf("HeartbeatPolicy.Interval must not be negative")
+	}
+	if p.MaxMissed < 0 {
+		return fmt.Errorf("HeartbeatPolicy.MaxMissed must not be negative")
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package layr8
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHeartbeatPolicy_Defaults(t *testing.T) {
+	p := HeartbeatPolicy{}.withDefaults()
+	if p.Interval != 30*time.Second {
+		t.Errorf("Interval = %v, want 30s", p.Interval)
+	}
+	if p.MaxMissed != 2 {
+		t.Errorf("MaxMissed = %d, want 2", p.MaxMissed)
+	}
+}
+
+func TestHeartbeatPolicy_Validate(t *testing.T) {
+	if err := (HeartbeatPolicy{Interval: -time.Second}).validate(); err == nil {
+		t.Error("negative Interval should be rejected")
+	}
+	if err := (HeartbeatPolicy{MaxMissed: -1}).validate(); err == nil {
+		t.Error("negative MaxMissed should be rejected")
+	}
+}
+
+func TestPhoenixChannel_HeartbeatLatency(t *testing.T) {
+	_, _, wsURL := setupMockServer(t) // replies ok to every ref'd event, including heartbeats
+
+	latency := make(chan time.Duration, 1)
+	ch := NewPhoenixTransport(Config{
+		NodeURL:  wsURL,
+		APIKey:   "test-key",
+		AgentDID: "did:web:test",
+		Heartbeat: HeartbeatPolicy{
+			Interval: 50 * time.Millisecond,
+			OnLatency: func(rtt time.Duration) {
+				select {
+				case latency <- rtt:
+				default:
+				}
+			},
+		},
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := ch.Connect(ctx, []string{}); err != nil {
+		t.Fatalf("Connect() error: %v", err)
+	}
+	defer ch.Close()
+
+	select {
+	case rtt := <-latency:
+		if rtt <= 0 || rtt > time.Second {
+			t.Errorf("heartbeat rtt = %v, want a small positive duration", rtt)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for heartbeat latency report")
+	}
+}
+
+func TestPhoenixChannel_MissedHeartbeatsForceReconnect(t *testing.T) {
+	mock := newMockServer()
+	mock.onMsg = func(msg phoenixMessage) {
+		// Answer joins but never heartbeats — simulates a half-open connection.
+		if msg.Event == "phx_join" {
+			mock.sendToClient(phoenixMessage{
+				JoinRef: msg.Ref,
+				Ref:     msg.Ref,
+				Topic:   msg.Topic,
+				Event:   "phx_reply",
+				Payload: json.RawMessage(`{"status":"ok","response":{}}`),
+			})
+		}
+	}
+	server := httptest.NewServer(http.HandlerFunc(mock.handler))
+	defer server.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/plugin_socket/websocket"
+	ch := NewPhoenixTransport(Config{
+		NodeURL:         wsURL,
+		APIKey:          "test-key",
+		AgentDID:        "did:web:test",
+		Heartbeat:       HeartbeatPolicy{Interval: 50 * time.Millisecond, MaxMissed: 2},
+		ReconnectPolicy: ReconnectPolicy{InitialDelay: 10 * time.Millisecond},
+	})
+
+	disconnected := make(chan error, 1)
+	reconnected := make(chan struct{}, 1)
+	ch.OnDisconnect(func(err error) {
+		select {
+		case disconnected <- err:
+		default:
+		}
+	})
+	ch.OnReconnect(func() {
+		select {
+		case reconnected <- struct{}{}:
+		default:
+		}
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := ch.Connect(ctx, []string{}); err != nil {
+		t.Fatalf("Connect() error: %v", err)
+	}
+	defer ch.Close()
+
+	select {
+	case err := <-disconnected:
+		if !strings.Contains(err.Error(), "heartbeats missed") {
+			t.Errorf("disconnect error = %v, want missed heartbeat reason", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for dead-connection detection")
+	}
+
+	select {
+	case <-reconnected:
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for reconnect after dead connection")
+	}
+}
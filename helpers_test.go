@@ -0,0 +1,62 @@
+package layr8
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+// discardErrors is a no-op ErrorHandler used in tests that don't assert error handler behavior.
+var discardErrors = func(SDKError) {}
+
+// newTestClient returns a client for alice that uses fake as its transport.
+// A nil onError discards errors. The client is closed when the test ends.
+func newTestClient(t *testing.T, fake *fakeTransport, cfg Config, onError ErrorHandler) *Client {
+	t.Helper()
+	cfg.NodeURL = "ws://localhost:4000/plugin_socket/websocket"
+	cfg.APIKey = "test-key"
+	cfg.AgentDID = "did:web:test:alice"
+	cfg.Transport = func(Config) Transport { return fake }
+	if onError == nil {
+		onError = discardErrors
+	}
+	client, err := NewClient(cfg, onError)
+	if err != nil {
+		t.Fatalf("NewClient() error: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func connectTestClient(t *testing.T, client *Client) {
+	t.Helper()
+	if err := client.Connect(context.Background()); err != nil {
+		t.Fatalf("Connect() error: %v", err)
+	}
+}
+
+// inboundPayload returns a node-wrapped inbound message from bob to alice
+// with an empty body.
+func inboundPayload(id, msgType, thid string) []byte {
+	return inboundPayloadWithBody(id, msgType, thid, map[string]string{})
+}
+
+func inboundPayloadWithBody(id, msgType, thid string, body any) []byte {
+	payload, _ := json.Marshal(map[string]any{
+		"plaintext": map[string]any{
+			"id":   id,
+			"type": msgType,
+			"from": "did:web:bob",
+			"to":   []string{"did:web:test:alice"},
+			"thid": thid,
+			"body": body,
+		},
+	})
+	return payload
+}
+
+func (f *fakeTransport) ackedIDs() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.acks...)
+}
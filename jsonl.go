@@ -0,0 +1,122 @@
+package layr8
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// jsonlFile is a JSON-lines file backing a file store. Appends and rewrites
+// are synced to disk before they return, so a record reported as stored
+// survives a crash.
+type jsonlFile struct {
+	path string
+	name string // store name for error messages, e.g. "outbound store"
+}
+
+// load calls fn with each line of the file, in order. A missing file has no lines.
+func (f jsonlFile) load(fn func(line []byte) error) error {
+	file, err := os.Open(f.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("open %s: %w", f.name, err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		if err := fn(scanner.Bytes()); err != nil {
+			return fmt.Errorf("decode %s %s: %w", f.name, f.path, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("read %s: %w", f.name, err)
+	}
+	return nil
+}
+
+// append writes v as a new line. The first append also syncs the directory,
+// so the newly created file itself is durable.
+func (f jsonlFile) append(v any) error {
+	line, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("encode %s record: %w", f.name, err)
+	}
+	_, statErr := os.Stat(f.path)
+	created := errors.Is(statErr, os.ErrNotExist)
+
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("open %s: %w", f.name, err)
+	}
+	_, err = file.Write(append(line, '\n'))
+	if err == nil {
+		err = file.Sync()
+	}
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil && created {
+		err = syncDir(filepath.Dir(f.path))
+	}
+	if err != nil {
+		return fmt.Errorf("write %s: %w", f.name, err)
+	}
+	return nil
+}
+
+// rewrite atomically replaces the file with the records written by write.
+// The new file is synced before the rename and the directory after it.
+func (f jsonlFile) rewrite(write func(enc *json.Encoder) error) error {
+	dir := filepath.Dir(f.path)
+	tmp, err := os.CreateTemp(dir, filepath.Base(f.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("rewrite %s: %w", f.name, err)
+	}
+	w := bufio.NewWriter(tmp)
+	err = write(json.NewEncoder(w))
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), f.path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("rewrite %s: %w", f.name, err)
+	}
+	if err := syncDir(dir); err != nil {
+		return fmt.Errorf("rewrite %s: %w", f.name, err)
+	}
+	return nil
+}
+
+// syncDir flushes a directory's entries, making created and renamed files
+// durable. Windows cannot sync directories; NTFS journals renames instead.
+func syncDir(dir string) error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	err = d.Sync()
+	if cerr := d.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
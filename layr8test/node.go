@@ -0,0 +1,303 @@
+// Package layr8test provides an in-process fake cloud-node for testing Layr8 agents.
+//
+// A Node routes DIDComm messages between any number of layr8.Clients by DID,
+// wraps inbound messages in the same context + plaintext envelope the real
+// cloud-node uses, records acknowledgments, and lets tests inject server
+// rejections and connection drops. No network connection is involved.
+//
+// Like the real cloud-node, a Node only delivers a message to an agent that
+// joined with the message's protocol, so a client must register a handler in
+// a protocol to receive messages — including responses to its own requests.
+//
+// Basic usage:
+//
+//	node := layr8test.NewNode()
+//
+//	echo, _ := layr8.NewClient(node.Config("did:web:test:echo"), layr8.LogErrors(log.Default()))
+//	echo.Handle(echoRequestType, echoHandler)
+//	echo.Connect(ctx)
+//
+//	caller, _ := layr8.NewClient(node.Config("did:web:test:caller"), layr8.LogErrors(log.Default()))
+//	caller.Handle(echoResponseType, ignoreUnsolicited)
+//	caller.Connect(ctx)
+//
+//	resp, err := caller.Request(ctx, &layr8.Message{
+//	    Type: echoRequestType,
+//	    To:   []string{"did:web:test:echo"},
+//	    Body: map[string]string{"message": "ping"},
+//	})
+package layr8test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	layr8 "github.com/layr8/go-sdk"
+)
+
+// NodeURL is the placeholder node URL used in configurations returned by Node.Config.
+const NodeURL = "ws://layr8test.invalid/plugin_socket/websocket"
+
+// Envelope is a DIDComm plaintext message as seen by the fake node.
+type Envelope struct {
+	ID             string          `json:"id"`
+	Type           string          `json:"type"`
+	From           string          `json:"from"`
+	To             []string        `json:"to"`
+	ThreadID       string          `json:"thid,omitempty"`
+	ParentThreadID string          `json:"pthid,omitempty"`
+	Body           json.RawMessage `json:"body"`
+}
+
+// UnmarshalBody decodes the envelope body into the provided value.
+func (e Envelope) UnmarshalBody(v any) error {
+	if e.Body == nil {
+		return errors.New("envelope has no body")
+	}
+	return json.Unmarshal(e.Body, v)
+}
+
+// Node is an in-process fake cloud-node. The zero value is not usable;
+// create nodes with NewNode. A Node is safe for concurrent use.
+type Node struct {
+	mu         sync.Mutex
+	conns      map[string]*conn // agent DID → joined connection
+	sent       []Envelope
+	acks       []string
+	reject     func(Envelope) error
+	authorized func(from, to string) bool
+	didCounter int
+	msgCounter int
+}
+
+// NewNode creates an empty fake cloud-node.
+func NewNode() *Node {
+	return &Node{
+		conns: make(map[string]*conn),
+	}
+}
+
+// Config returns a layr8.Config that connects a client to this node.
+// If agentDID is empty, the node assigns an ephemeral DID on Connect.
+func (n *Node) Config(agentDID string) layr8.Config {
+	return layr8.Config{
+		NodeURL:   NodeURL,
+		APIKey:    "layr8test",
+		AgentDID:  agentDID,
+		Transport: n.Transport,
+	}
+}
+
+// Transport is a layr8.TransportFactory that creates connections to this node.
+// Use it as Config.Transport when building a configuration by hand.
+func (n *Node) Transport(cfg layr8.Config) layr8.Transport {
+	return newConn(n, cfg.AgentDID)
+}
+
+// Reject installs a hook that is consulted for every message sent through the node.
+// A non-nil error rejects the message: the sender receives a server reply with
+// status "error" and the error text as reason. Pass nil to accept all messages.
+func (n *Node) Reject(fn func(Envelope) error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.reject = fn
+}
+
+// SetAuthorizer controls the "authorized" flag of the message context delivered
+// to recipients. By default every message is authorized.
+func (n *Node) SetAuthorizer(fn func(from, to string) bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.authorized = fn
+}
+
+// Inject delivers msg to its recipients as if it had been sent by a remote agent.
+// Missing IDs are generated; the body is marshaled to JSON.
+func (n *Node) Inject(msg *layr8.Message) error {
+	env := Envelope{
+		ID:             msg.ID,
+		Type:           msg.Type,
+		From:           msg.From,
+		To:             msg.To,
+		ThreadID:       msg.ThreadID,
+		ParentThreadID: msg.ParentThreadID,
+		Body:           json.RawMessage(`{}`),
+	}
+	if env.ID == "" {
+		n.mu.Lock()
+		n.msgCounter++
+		env.ID = fmt.Sprintf("layr8test-msg-%d", n.msgCounter)
+		n.mu.Unlock()
+	}
+	if msg.Body != nil {
+		b, err := json.Marshal(msg.Body)
+		if err != nil {
+			return fmt.Errorf("marshal body: %w", err)
+		}
+		env.Body = b
+	}
+	n.route(env)
+	return nil
+}
+
+// Sent returns every message sent by connected clients, in order.
+func (n *Node) Sent() []Envelope {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return slices.Clone(n.sent)
+}
+
+// Acks returns every acknowledged message ID, in order.
+func (n *Node) Acks() []string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return slices.Clone(n.acks)
+}
+
+// Acked reports whether the message with the given ID has been acknowledged.
+func (n *Node) Acked(id string) bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return slices.Contains(n.acks, id)
+}
+
+// Protocols returns the protocols the agent with the given DID joined with.
+func (n *Node) Protocols(did string) []string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	c, ok := n.conns[did]
+	if !ok {
+		return nil
+	}
+	return c.joinedProtocols()
+}
+
+// Disconnect simulates a dropped connection for the agent with the given DID.
+// The client's OnDisconnect callback fires with err and sends fail with
+// layr8.ErrNotConnected until Reconnect is called; a send in flight when the
+// connection drops (e.g. from a Reject hook) returns layr8.ErrConnectionLost
+// although the node has already routed it. Messages routed to the
+// agent while disconnected are held and delivered on Reconnect.
+func (n *Node) Disconnect(did string, err error) error {
+	c, err2 := n.lookup(did)
+	if err2 != nil {
+		return err2
+	}
+	if err == nil {
+		err = errors.New("layr8test: connection dropped")
+	}
+	c.disconnect(err)
+	return nil
+}
+
+// Reconnect restores a connection dropped with Disconnect. The client's
+// OnReconnect callback fires, and every message delivered to the agent that
+// was not yet acknowledged is redelivered, as the real cloud-node does.
+func (n *Node) Reconnect(did string) error {
+	c, err := n.lookup(did)
+	if err != nil {
+		return err
+	}
+	c.reconnect()
+	return nil
+}
+
+func (n *Node) lookup(did string) (*conn, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	c, ok := n.conns[did]
+	if !ok {
+		return nil, fmt.Errorf("layr8test: no agent connected as %q", did)
+	}
+	return c, nil
+}
+
+// join registers a connection under its DID, assigning an ephemeral DID if needed.
+func (n *Node) join(c *conn) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if c.did == "" {
+		n.didCounter++
+		c.did = fmt.Sprintf("did:web:layr8test:agent-%d", n.didCounter)
+	}
+	if _, exists := n.conns[c.did]; exists {
+		return &layr8.ConnectionError{URL: NodeURL, Reason: fmt.Sprintf("e.connect.plugin.failed: %s already connected", c.did)}
+	}
+	n.conns[c.did] = c
+	return nil
+}
+
+func (n *Node) leave(c *conn) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.conns[c.did] == c {
+		delete(n.conns, c.did)
+	}
+}
+
+// submit records an outbound message and routes it unless the reject hook refuses it.
+func (n *Node) submit(env Envelope) layr8.ServerReply {
+	n.mu.Lock()
+	n.sent = append(n.sent, env)
+	reject := n.reject
+	n.mu.Unlock()
+
+	if reject != nil {
+		if err := reject(env); err != nil {
+			return layr8.ServerReply{Status: "error", Reason: err.Error()}
+		}
+	}
+	n.route(env)
+	return layr8.ServerReply{Status: "ok"}
+}
+
+// route delivers env to every connected recipient that joined with the message's protocol.
+// Messages for unknown DIDs are dropped, as they would leave the node in production.
+func (n *Node) route(env Envelope) {
+	plaintext, err := json.Marshal(env)
+	if err != nil {
+		return
+	}
+
+	n.mu.Lock()
+	authorized := n.authorized
+	var targets []*conn
+	for _, to := range env.To {
+		if c, ok := n.conns[to]; ok && c.accepts(env.Type) {
+			targets = append(targets, c)
+		}
+	}
+	n.mu.Unlock()
+
+	for _, c := range targets {
+		payload, _ := json.Marshal(map[string]any{
+			"context": map[string]any{
+				"recipient":          c.did,
+				"authorized":         authorized == nil || authorized(env.From, c.did),
+				"sender_credentials": []any{},
+			},
+			"plaintext": json.RawMessage(plaintext),
+		})
+		c.deliver(env.ID, payload)
+	}
+}
+
+func (n *Node) recordAcks(ids []string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.acks = append(n.acks, ids...)
+}
+
+// acceptsProtocol reports whether msgType belongs to one of the joined protocols.
+func acceptsProtocol(protocols []string, msgType string) bool {
+	for _, p := range protocols {
+		if strings.HasPrefix(msgType, p+"/") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,286 @@
+package layr8test_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	layr8 "github.com/layr8/go-sdk"
+	"github.com/layr8/go-sdk/layr8test"
+)
+
+const (
+	echoRequestType  = "https://layr8.io/protocols/echo/1.0/request"
+	echoResponseType = "https://layr8.io/protocols/echo/1.0/response"
+)
+
+var discardErrors = func(layr8.SDKError) {}
+
+func newEchoAgent(t *testing.T, node *layr8test.Node, did string, opts ...layr8.HandlerOption) *layr8.Client {
+	t.Helper()
+	client, err := layr8.NewClient(node.Config(did), discardErrors)
+	if err != nil {
+		t.Fatalf("NewClient() error: %v", err)
+	}
+	client.Handle(echoRequestType, func(msg *layr8.Message) (*layr8.Message, error) {
+		var req struct {
+			Message string `json:"message"`
+		}
+		if err := msg.UnmarshalBody(&req); err != nil {
+			return nil, err
+		}
+		msg.Ack()
+		return &layr8.Message{
+			Type: echoResponseType,
+			Body: map[string]string{"echo": req.Message},
+		}, nil
+	}, opts...)
+	connect(t, client)
+	return client
+}
+
+func connect(t *testing.T, client *layr8.Client) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Connect(ctx); err != nil {
+		t.Fatalf("Connect() error: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+}
+
+func TestNode_RequestResponse(t *testing.T) {
+	node := layr8test.NewNode()
+	newEchoAgent(t, node, "did:web:test:echo")
+
+	// Like the real node, responses are only routed for protocols the caller joined with.
+	caller, _ := layr8.NewClient(node.Config("did:web:test:caller"), discardErrors)
+	caller.Handle(echoResponseType, func(msg *layr8.Message) (*layr8.Message, error) { return nil, nil })
+	connect(t, caller)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	resp, err := caller.Request(ctx, &layr8.Message{
+		Type: echoRequestType,
+		To:   []string{"did:web:test:echo"},
+		Body: map[string]string{"message": "ping"},
+	})
+	if err != nil {
+		t.Fatalf("Request() error: %v", err)
+	}
+
+	var body map[string]string
+	resp.UnmarshalBody(&body)
+	if body["echo"] != "ping" {
+		t.Errorf("echo = %q, want %q", body["echo"], "ping")
+	}
+	if resp.From != "did:web:test:echo" {
+		t.Errorf("resp.From = %q, want %q", resp.From, "did:web:test:echo")
+	}
+	if resp.Context == nil || resp.Context.Recipient != "did:web:test:caller" || !resp.Context.Authorized {
+		t.Errorf("resp.Context = %+v, want recipient=caller authorized=true", resp.Context)
+	}
+
+	sent := node.Sent()
+	if len(sent) != 2 {
+		t.Fatalf("Sent() len = %d, want 2 (request + response)", len(sent))
+	}
+	if sent[1].ThreadID != sent[0].ThreadID {
+		t.Errorf("response thid = %q, want request thid %q", sent[1].ThreadID, sent[0].ThreadID)
+	}
+}
+
+func TestNode_AssignsEphemeralDID(t *testing.T) {
+	node := layr8test.NewNode()
+	client, _ := layr8.NewClient(node.Config(""), discardErrors)
+	connect(t, client)
+
+	if client.DID() == "" {
+		t.Fatal("DID() should be assigned by the node")
+	}
+	if got := node.Protocols(client.DID()); len(got) != 1 {
+		t.Errorf("Protocols() = %v, want only report-problem", got)
+	}
+}
+
+func TestNode_InjectAndAck(t *testing.T) {
+	node := layr8test.NewNode()
+	newEchoAgent(t, node, "did:web:test:echo")
+
+	node.Inject(&layr8.Message{
+		ID:   "inbound-1",
+		Type: echoRequestType,
+		From: "did:web:remote:bob",
+		To:   []string{"did:web:test:echo"},
+		Body: map[string]string{"message": "hi"},
+	})
+
+	waitFor(t, func() bool { return node.Acked("inbound-1") })
+	waitFor(t, func() bool { return len(node.Sent()) == 1 })
+
+	resp := node.Sent()[0]
+	if resp.Type != echoResponseType || len(resp.To) != 1 || resp.To[0] != "did:web:remote:bob" {
+		t.Errorf("response = %+v, want echo response to did:web:remote:bob", resp)
+	}
+}
+
+func TestNode_Reject(t *testing.T) {
+	node := layr8test.NewNode()
+	client, _ := layr8.NewClient(node.Config("did:web:test:alice"), discardErrors)
+	connect(t, client)
+
+	node.Reject(func(env layr8test.Envelope) error {
+		return errors.New("unauthorized")
+	})
+
+	err := client.Send(context.Background(), &layr8.Message{
+		Type: "https://didcomm.org/basicmessage/2.0/message",
+		To:   []string{"did:web:test:bob"},
+	})
+	if err == nil {
+		t.Fatal("Send() should return the injected rejection")
+	}
+}
+
+func TestNode_DisconnectAndRedeliver(t *testing.T) {
+	node := layr8test.NewNode()
+
+	client, _ := layr8.NewClient(node.Config("did:web:test:worker"), discardErrors)
+	attempts := make(chan string, 4)
+	client.Handle(echoRequestType, func(msg *layr8.Message) (*layr8.Message, error) {
+		attempts <- msg.ID
+		return nil, nil // never acks
+	}, layr8.WithManualAck())
+
+	disconnected := make(chan error, 1)
+	reconnected := make(chan struct{}, 1)
+	client.OnDisconnect(func(err error) { disconnected <- err })
+	client.OnReconnect(func() { reconnected <- struct{}{} })
+	connect(t, client)
+
+	node.Inject(&layr8.Message{ID: "job-1", Type: echoRequestType, To: []string{"did:web:test:worker"}})
+	expectID(t, attempts, "job-1")
+
+	node.Disconnect("did:web:test:worker", nil)
+	select {
+	case <-disconnected:
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for OnDisconnect")
+	}
+
+	if err := client.Send(context.Background(), &layr8.Message{Type: echoRequestType}); !errors.Is(err, layr8.ErrNotConnected) {
+		t.Errorf("Send() while disconnected = %v, want ErrNotConnected", err)
+	}
+
+	node.Reconnect("did:web:test:worker")
+	select {
+	case <-reconnected:
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for OnReconnect")
+	}
+
+	// Unacked message is redelivered after reconnect.
+	expectID(t, attempts, "job-1")
+}
+
+func expectID(t *testing.T, ch <-chan string, want string) {
+	t.Helper()
+	select {
+	case got := <-ch:
+		if got != want {
+			t.Errorf("handled %q, want %q", got, want)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("timeout waiting for %q", want)
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("condition not met before timeout")
+}
+
+func TestNode_MajorVersionRangeJoinsListedMinors(t *testing.T) {
+	node := layr8test.NewNode()
+	client, _ := layr8.NewClient(node.Config("did:web:test:echo"), discardErrors)
+	handled := make(chan string, 3)
+	handle := func(msg *layr8.Message) (*layr8.Message, error) {
+		handled <- msg.Type
+		return nil, nil
+	}
+	client.HandleProtocol("https://layr8.io/protocols/echo/1.x", handle, layr8.WithMinorVersions(0, 3))
+	connect(t, client)
+
+	got := node.Protocols("did:web:test:echo")
+	for _, want := range []string{"https://layr8.io/protocols/echo/1.0", "https://layr8.io/protocols/echo/1.3"} {
+		if !slices.Contains(got, want) {
+			t.Fatalf("Protocols() = %v, want %s", got, want)
+		}
+	}
+	inject := func(id, msgType string) {
+		node.Inject(&layr8.Message{ID: id, Type: msgType, From: "did:web:remote:bob", To: []string{"did:web:test:echo"}})
+	}
+	// The node routes by exact protocol: 1.2 is not registered, so only
+	// the listed minor versions reach the range handler.
+	inject("inbound-1", "https://layr8.io/protocols/echo/1.2/request")
+	inject("inbound-2", "https://layr8.io/protocols/echo/1.3/request")
+	expectID(t, handled, "https://layr8.io/protocols/echo/1.3/request")
+	inject("inbound-3", "https://layr8.io/protocols/echo/1.0/request")
+	expectID(t, handled, "https://layr8.io/protocols/echo/1.0/request")
+}
+
+func TestNode_RequestRetriesAfterMidRequestDrop(t *testing.T) {
+	node := layr8test.NewNode()
+	newEchoAgent(t, node, "did:web:test:echo")
+
+	caller, _ := layr8.NewClient(node.Config("did:web:test:caller"), discardErrors)
+	caller.Handle(echoResponseType, func(msg *layr8.Message) (*layr8.Message, error) { return nil, nil })
+	connect(t, caller)
+
+	// Drop the caller's connection while the node handles its first request,
+	// so the send returns without a reply; bring it back shortly after.
+	var dropped sync.Once
+	node.Reject(func(env layr8test.Envelope) error {
+		if env.Type == echoRequestType {
+			dropped.Do(func() {
+				node.Disconnect("did:web:test:caller", nil)
+				time.AfterFunc(50*time.Millisecond, func() { node.Reconnect("did:web:test:caller") })
+			})
+		}
+		return nil
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	resp, err := caller.Request(ctx, &layr8.Message{
+		Type: echoRequestType,
+		To:   []string{"did:web:test:echo"},
+		Body: map[string]string{"message": "ping"},
+	}, layr8.WithRetry(2, time.Second))
+	if err != nil {
+		t.Fatalf("Request() error: %v", err)
+	}
+	if resp.Type != echoResponseType {
+		t.Errorf("resp.Type = %q, want %q", resp.Type, echoResponseType)
+	}
+
+	var requests []string
+	for _, env := range node.Sent() {
+		if env.Type == echoRequestType {
+			requests = append(requests, env.ID)
+		}
+	}
+	if len(requests) != 2 || requests[0] != requests[1] {
+		t.Errorf("request IDs sent = %v, want the same ID resent once", requests)
+	}
+}
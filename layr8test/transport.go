@@ -0,0 +1,262 @@
+package layr8test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sync"
+
+	layr8 "github.com/layr8/go-sdk"
+)
+
+// conn is a client's connection to a Node. It implements layr8.Transport.
+type conn struct {
+	node *Node
+	did  string // requested DID, replaced by the assigned one on join
+
+	mu           sync.Mutex
+	protocols    []string // set on Connect and UpdateProtocols
+	connected    bool
+	closed       bool
+	handler      func(payload []byte)
+	disconnectFn func(error)
+	reconnectFn  func()
+	queue        [][]byte         // inbound payloads waiting for the delivery goroutine
+	unacked      []unackedMessage // routed but not yet acknowledged, in routing order
+
+	notify chan struct{}
+	done   chan struct{}
+}
+
+type unackedMessage struct {
+	id      string
+	payload []byte
+}
+
+func newConn(n *Node, did string) *conn {
+	return &conn{
+		node:   n,
+		did:    did,
+		notify: make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+}
+
+func (c *conn) Connect(ctx context.Context, protocols []string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	c.protocols = slices.Clone(protocols)
+	c.mu.Unlock()
+	if err := c.node.join(c); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.connected = true
+	c.mu.Unlock()
+
+	go c.deliveryLoop()
+	return nil
+}
+
+// UpdateProtocols replaces the joined protocols. Unlike a real rejoin nothing
+// is torn down, so messages in flight are unaffected.
+func (c *conn) UpdateProtocols(ctx context.Context, protocols []string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.protocols = slices.Clone(protocols)
+	return nil
+}
+
+func (c *conn) Send(ctx context.Context, event string, payload []byte) (layr8.ServerReply, error) {
+	if err := ctx.Err(); err != nil {
+		return layr8.ServerReply{}, err
+	}
+	if !c.isConnected() {
+		return layr8.ServerReply{}, layr8.ErrNotConnected
+	}
+	if event != "message" {
+		return layr8.ServerReply{Status: "ok"}, nil
+	}
+
+	var env Envelope
+	if err := json.Unmarshal(payload, &env); err != nil {
+		return layr8.ServerReply{Status: "error", Reason: fmt.Sprintf("invalid message: %v", err)}, nil
+	}
+	reply := c.node.submit(env)
+	if !c.isConnected() {
+		// Dropped while the node handled the message: the reply is lost.
+		return layr8.ServerReply{}, layr8.ErrConnectionLost
+	}
+	return reply, nil
+}
+
+func (c *conn) SendFireAndForget(event string, payload []byte) error {
+	_, err := c.Send(context.Background(), event, payload)
+	return err
+}
+
+func (c *conn) SendAck(ids []string) error {
+	if !c.isConnected() {
+		return layr8.ErrNotConnected
+	}
+	c.mu.Lock()
+	c.unacked = slices.DeleteFunc(c.unacked, func(m unackedMessage) bool {
+		return slices.Contains(ids, m.id)
+	})
+	c.mu.Unlock()
+
+	c.node.recordAcks(ids)
+	return nil
+}
+
+func (c *conn) SetMessageHandler(fn func(payload []byte)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.handler = fn
+}
+
+func (c *conn) OnDisconnect(fn func(error)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.disconnectFn = fn
+}
+
+func (c *conn) OnReconnect(fn func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.reconnectFn = fn
+}
+
+func (c *conn) AssignedDID() string {
+	return c.did
+}
+
+func (c *conn) NodeURL() string {
+	return NodeURL
+}
+
+func (c *conn) Close() error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil
+	}
+	c.closed = true
+	c.connected = false
+	c.mu.Unlock()
+
+	close(c.done)
+	c.node.leave(c)
+	return nil
+}
+
+func (c *conn) isConnected() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.connected
+}
+
+func (c *conn) accepts(msgType string) bool {
+	return acceptsProtocol(c.joinedProtocols(), msgType)
+}
+
+func (c *conn) joinedProtocols() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return slices.Clone(c.protocols)
+}
+
+// deliver queues an inbound payload for the client. While disconnected the
+// payload is only tracked as unacked and goes out on reconnect.
+func (c *conn) deliver(id string, payload []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return
+	}
+	c.unacked = append(c.unacked, unackedMessage{id: id, payload: payload})
+	if !c.connected {
+		return
+	}
+	c.queue = append(c.queue, payload)
+	c.signal()
+}
+
+func (c *conn) disconnect(err error) {
+	c.mu.Lock()
+	if !c.connected {
+		c.mu.Unlock()
+		return
+	}
+	c.connected = false
+	c.queue = nil // still tracked in unacked
+	fn := c.disconnectFn
+	c.mu.Unlock()
+
+	if fn != nil {
+		fn(err)
+	}
+}
+
+func (c *conn) reconnect() {
+	c.mu.Lock()
+	if c.connected || c.closed {
+		c.mu.Unlock()
+		return
+	}
+	c.connected = true
+	// Redeliver everything that was not acknowledged, in original order.
+	for _, m := range c.unacked {
+		c.queue = append(c.queue, m.payload)
+	}
+	c.signal()
+	fn := c.reconnectFn
+	c.mu.Unlock()
+
+	if fn != nil {
+		fn()
+	}
+}
+
+// signal wakes the delivery goroutine. Must be called with c.mu held.
+func (c *conn) signal() {
+	select {
+	case c.notify <- struct{}{}:
+	default:
+	}
+}
+
+// deliveryLoop hands queued payloads to the message handler one at a time,
+// mirroring the single read loop of a WebSocket transport.
+func (c *conn) deliveryLoop() {
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-c.notify:
+		}
+
+		for {
+			c.mu.Lock()
+			if len(c.queue) == 0 || !c.connected {
+				c.mu.Unlock()
+				break
+			}
+			payload := c.queue[0]
+			c.queue = c.queue[1:]
+			handler := c.handler
+			c.mu.Unlock()
+
+			if handler != nil {
+				handler(payload)
+			}
+		}
+	}
+}
@@ -21,7 +21,9 @@ type Message struct {
 
 	// Internal fields
 	bodyRaw json.RawMessage // raw JSON body for lazy deserialization
-	ackFn   func(id string) // set by client for manual ack
+	raw     []byte          // inbound envelope as received, kept for dead letters
+	ack     *ackControl     // set by client when a handler starts
+	claimed bool            // ID claimed for deduplication until the handler ends
 }
 
 // MessageContext contains metadata from the cloud-node, present on inbound messages.
@@ -47,10 +49,9 @@ func (m *Message) UnmarshalBody(v any) error {
 
 // Ack acknowledges this message to the cloud-node.
 // Only meaningful when the handler was registered with WithManualAck().
+// A message is settled once: after Ack, Nack or Reject, further calls are ignored.
 func (m *Message) Ack() {
-	if m.ackFn != nil {
-		m.ackFn(m.ID)
-	}
+	m.settle(AckStateAcked, 0, "")
 }
 
 // generateID returns a new unique message ID.
@@ -2,7 +2,9 @@ package layr8
 
 import (
 	"encoding/json"
+	"slices"
 	"testing"
+	"time"
 )
 
 type testBody struct {
@@ -170,14 +172,20 @@ func TestParseDIDComm(t *testing.T) {
 }
 
 func TestMessage_Ack(t *testing.T) {
-	acked := false
+	var settled []AckState
 	msg := &Message{
-		ID:    "msg-1",
-		ackFn: func(id string) { acked = true },
+		ID: "msg-1",
+		ack: &ackControl{settle: func(state AckState, delay time.Duration, reason string) {
+			settled = append(settled, state)
+		}},
 	}
 	msg.Ack()
-	if !acked {
-		t.Error("Ack() should call ackFn")
+	msg.Reject("too late") // already settled: ignored
+	if !slices.Equal(settled, []AckState{AckStateAcked}) {
+		t.Errorf("settled = %v, want [acked]", settled)
+	}
+	if !msg.Acked() || msg.AckState() != AckStateAcked {
+		t.Errorf("Acked() = %v, AckState() = %v; want acked", msg.Acked(), msg.AckState())
 	}
 }
 
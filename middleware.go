@@ -0,0 +1,111 @@
+package layr8
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// Middleware wraps a handler with cross-cutting behavior such as logging,
+// authorization checks or metrics. It returns a handler that typically does
+// its work and then calls next.
+type Middleware func(next HandlerFuncCtx) HandlerFuncCtx
+
+// Use adds middleware that wraps every handler, including handlers
+// registered earlier. Middleware runs in the order it was added, outside any
+// per-handler middleware set with WithMiddleware.
+func (c *Client) Use(middleware ...Middleware) {
+	c.middlewareMu.Lock()
+	defer c.middlewareMu.Unlock()
+	c.middleware = append(c.middleware, middleware...)
+}
+
+// chain wraps fn with the client-wide middleware and then entry's own, so the
+// first middleware passed to Use is the outermost.
+func (c *Client) chain(entry handlerEntry) HandlerFuncCtx {
+	c.middlewareMu.RLock()
+	defer c.middlewareMu.RUnlock()
+
+	fn := entry.fn
+	for i := len(entry.middleware) - 1; i >= 0; i-- {
+		fn = entry.middleware[i](fn)
+	}
+	for i := len(c.middleware) - 1; i >= 0; i-- {
+		fn = c.middleware[i](fn)
+	}
+	return fn
+}
+
+// LogRequests logs every handled message and its outcome. A nil logger uses
+// slog.Default().
+func LogRequests(logger *slog.Logger) Middleware {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return func(next HandlerFuncCtx) HandlerFuncCtx {
+		return func(ctx context.Context, msg *Message) (*Message, error) {
+			start := time.Now()
+			resp, err := next(ctx, msg)
+			attrs := []any{
+				"type", msg.Type,
+				"id", msg.ID,
+				"from", msg.From,
+				"thid", msg.ThreadID,
+				"duration", time.Since(start),
+			}
+			if err != nil {
+				logger.WarnContext(ctx, "handler failed", append(attrs, "error", err)...)
+			} else {
+				logger.InfoContext(ctx, "handled message", attrs...)
+			}
+			return resp, err
+		}
+	}
+}
+
+// Recover turns a handler panic into an error, so the sender receives an
+// ordinary problem report. Without it, panics are still recovered but are
+// also reported to the ErrorHandler as ErrHandlerPanic.
+func Recover() Middleware {
+	return func(next HandlerFuncCtx) HandlerFuncCtx {
+		return func(ctx context.Context, msg *Message) (resp *Message, err error) {
+			defer func() {
+				if r := recover(); r != nil {
+					resp, err = nil, fmt.Errorf("handler panic: %v", r)
+				}
+			}()
+			return next(ctx, msg)
+		}
+	}
+}
+
+// RequireAuthorized rejects messages the cloud-node did not mark as
+// authorized (MessageContext.Authorized) with an "e.p.trust.unauthorized"
+// problem report, without calling the handler.
+func RequireAuthorized() Middleware {
+	return func(next HandlerFuncCtx) HandlerFuncCtx {
+		return func(ctx context.Context, msg *Message) (*Message, error) {
+			if msg.Context == nil || !msg.Context.Authorized {
+				return nil, &ProblemReportError{
+					Code:    "e.p.trust.unauthorized",
+					Comment: "sender {1} is not authorized",
+					Args:    []string{msg.From},
+				}
+			}
+			return next(ctx, msg)
+		}
+	}
+}
+
+// Timeout bounds the context passed to the rest of the chain to d, like
+// WithTimeout but applicable to every handler through Use.
+func Timeout(d time.Duration) Middleware {
+	return func(next HandlerFuncCtx) HandlerFuncCtx {
+		return func(ctx context.Context, msg *Message) (*Message, error) {
+			ctx, cancel := context.WithTimeout(ctx, d)
+			defer cancel()
+			return next(ctx, msg)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package layr8
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func tagMiddleware(mu *sync.Mutex, calls *[]string, tag string) Middleware {
+	return func(next HandlerFuncCtx) HandlerFuncCtx {
+		return func(ctx context.Context, msg *Message) (*Message, error) {
+			mu.Lock()
+			*calls = append(*calls, tag)
+			mu.Unlock()
+			return next(ctx, msg)
+		}
+	}
+}
+
+func sentProblem(t *testing.T, fake *fakeTransport) ProblemReportError {
+	t.Helper()
+	waitForSent(t, fake, 1)
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	var report struct {
+		Type string             `json:"type"`
+		Body ProblemReportError `json:"body"`
+	}
+	json.Unmarshal(fake.sent[0], &report)
+	if report.Type != "https://didcomm.org/report-problem/2.0/problem-report" {
+		t.Fatalf("sent %q, want a problem report", report.Type)
+	}
+	return report.Body
+}
+
+func TestClient_Use_MiddlewareOrder(t *testing.T) {
+	fake := &fakeTransport{}
+	client := newTestClient(t, fake, Config{}, nil)
+
+	var mu sync.Mutex
+	var calls []string
+	done := make(chan struct{})
+	client.Handle(dispatchTypeA, func(msg *Message) (*Message, error) {
+		mu.Lock()
+		calls = append(calls, "handler")
+		mu.Unlock()
+		close(done)
+		return nil, nil
+	}, WithMiddleware(tagMiddleware(&mu, &calls, "handler-1"), tagMiddleware(&mu, &calls, "handler-2")))
+	// Client-wide middleware also applies to handlers registered earlier.
+	client.Use(tagMiddleware(&mu, &calls, "client-1"))
+	client.Use(tagMiddleware(&mu, &calls, "client-2"))
+	connectTestClient(t, client)
+
+	fake.handler(inboundPayload("m1", dispatchTypeA, ""))
+	<-done
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := []string{"client-1", "client-2", "handler-1", "handler-2", "handler"}
+	if !slices.Equal(calls, want) {
+		t.Errorf("calls = %v, want %v", calls, want)
+	}
+}
+
+func TestRequireAuthorized(t *testing.T) {
+	fake := &fakeTransport{}
+	client := newTestClient(t, fake, Config{}, nil)
+	handled := make(chan string, 2)
+	client.Handle(dispatchTypeA, func(msg *Message) (*Message, error) {
+		handled <- msg.ID
+		return nil, nil
+	}, WithMiddleware(RequireAuthorized()))
+	connectTestClient(t, client)
+
+	authorized, _ := json.Marshal(map[string]any{
+		"context": map[string]any{"recipient": "did:web:test:alice", "authorized": true},
+		"plaintext": map[string]any{
+			"id": "ok", "type": dispatchTypeA, "from": "did:web:bob",
+			"to": []string{"did:web:test:alice"}, "body": map[string]string{},
+		},
+	})
+	fake.handler(authorized)
+	if id := <-handled; id != "ok" {
+		t.Fatalf("handled %q, want ok", id)
+	}
+
+	fake.handler(inboundPayload("denied", dispatchTypeA, ""))
+	prob := sentProblem(t, fake)
+	if prob.Code != "e.p.trust.unauthorized" {
+		t.Errorf("problem code = %q, want e.p.trust.unauthorized", prob.Code)
+	}
+	select {
+	case id := <-handled:
+		t.Errorf("handler called for %q without authorization", id)
+	default:
+	}
+}
+
+func TestRecover(t *testing.T) {
+	fake := &fakeTransport{}
+	var mu sync.Mutex
+	var reported []SDKError
+	client := newTestClient(t, fake, Config{}, func(err SDKError) {
+		mu.Lock()
+		reported = append(reported, err)
+		mu.Unlock()
+	})
+	client.Use(Recover())
+	client.Handle(dispatchTypeA, func(msg *Message) (*Message, error) {
+		panic("boom")
+	})
+	connectTestClient(t, client)
+
+	fake.handler(inboundPayload("m1", dispatchTypeA, ""))
+	prob := sentProblem(t, fake)
+	if prob.Code != "e.p.xfer.cant-process" || !strings.Contains(prob.Comment, "boom") {
+		t.Errorf("problem report = %+v, want cant-process mentioning the panic", prob)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(reported) != 0 {
+		t.Errorf("ErrorHandler got %v, want no ErrHandlerPanic once recovered", reported)
+	}
+}
+
+func TestLogRequests(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, nil))
+	fn := LogRequests(logger)(func(ctx context.Context, msg *Message) (*Message, error) {
+		return nil, nil
+	})
+
+	fn(context.Background(), &Message{ID: "m1", Type: dispatchTypeA, From: "did:web:bob"})
+
+	out := buf.String()
+	for _, want := range []string{"handled message", "id=m1", "from=did:web:bob", "type=" + dispatchTypeA} {
+		if !strings.Contains(out, want) {
+			t.Errorf("log output %q missing %q", out, want)
+		}
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	fn := Timeout(time.Second)(func(ctx context.Context, msg *Message) (*Message, error) {
+		deadline, ok := ctx.Deadline()
+		if !ok || time.Until(deadline) > time.Second {
+			t.Errorf("Deadline() = %v, %v; want within 1s", deadline, ok)
+		}
+		return nil, nil
+	})
+	fn(context.Background(), &Message{})
+}
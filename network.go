@@ -0,0 +1,82 @@
+package layr8
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+)
+
+// DialContextFunc dials a network connection, matching net.Dialer.DialContext.
+type DialContextFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// netConfig holds the network settings shared by the Phoenix channel and the
+// REST client, so both reach the cloud-node the same way.
+type netConfig struct {
+	tlsConfig   *tls.Config
+	proxy       func(*http.Request) (*url.URL, error) // nil means no proxy
+	dialContext DialContextFunc
+}
+
+// newNetConfig builds the shared network settings from a resolved Config.
+func newNetConfig(cfg Config) (netConfig, error) {
+	n := netConfig{
+		tlsConfig:   cfg.TLSConfig,
+		dialContext: cfg.NetDialContext,
+	}
+	if cfg.ProxyURL != "" {
+		u, err := parseProxyURL(cfg.ProxyURL)
+		if err != nil {
+			return n, err
+		}
+		n.proxy = http.ProxyURL(u)
+	}
+	return n, nil
+}
+
+func parseProxyURL(raw string) (*url.URL, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("ProxyURL: %w", err)
+	}
+	// The WebSocket dialer only speaks plain HTTP CONNECT and SOCKS5 to the
+	// proxy, so a TLS ("https") proxy is rejected rather than half supported.
+	switch u.Scheme {
+	case "http", "socks5":
+	default:
+		return nil, fmt.Errorf("ProxyURL: unsupported scheme %q (want http or socks5)", u.Scheme)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("ProxyURL: missing host in %q", raw)
+	}
+	return u, nil
+}
+
+// dial returns the dial function for both clients.
+// It resolves *.localhost to loopback (RFC 6761) before dialing — Go's net
+// package doesn't implement this, unlike curl and browsers — and then uses the
+// custom NetDialContext, if any.
+func (n netConfig) dial() DialContextFunc {
+	next := n.dialContext
+	if next == nil {
+		next = (&net.Dialer{}).DialContext
+	}
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err == nil && isLocalhost(host) {
+			addr = net.JoinHostPort("127.0.0.1", port)
+		}
+		return next(ctx, network, addr)
+	}
+}
+
+// httpTransport returns an http.Transport for the REST client.
+func (n netConfig) httpTransport() *http.Transport {
+	return &http.Transport{
+		Proxy:           n.proxy,
+		DialContext:     n.dial(),
+		TLSClientConfig: n.tlsConfig,
+	}
+}
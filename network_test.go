@@ -0,0 +1,130 @@
+package layr8
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newJoiningMockServer returns a mock node that accepts phx_join.
+func newJoiningMockServer() *mockPhoenixServer {
+	mock := newMockServer()
+	mock.onMsg = func(msg phoenixMessage) {
+		if msg.Event == "phx_join" {
+			mock.sendToClient(phoenixMessage{
+				JoinRef: msg.Ref,
+				Ref:     msg.Ref,
+				Topic:   msg.Topic,
+				Event:   "phx_reply",
+				Payload: json.RawMessage(`{"status":"ok","response":{}}`),
+			})
+		}
+	}
+	return mock
+}
+
+func TestPhoenixChannel_TLSConfig_PrivateCA(t *testing.T) {
+	mock := newJoiningMockServer()
+	server := httptest.NewTLSServer(http.HandlerFunc(mock.handler))
+	defer server.Close()
+
+	wsURL := "wss" + strings.TrimPrefix(server.URL, "https") + "/plugin_socket/websocket"
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// Without the server's CA the handshake fails.
+	ch := newPhoenixChannel(wsURL, "test-key", "did:web:test")
+	if err := ch.Connect(ctx, nil); err == nil {
+		ch.Close()
+		t.Fatal("Connect() should fail without trusting the server certificate")
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+	ch = NewPhoenixTransport(Config{
+		NodeURL:   wsURL,
+		APIKey:    "test-key",
+		AgentDID:  "did:web:test",
+		TLSConfig: &tls.Config{RootCAs: roots},
+	}).(*phoenixChannel)
+	if err := ch.Connect(ctx, nil); err != nil {
+		t.Fatalf("Connect() with private CA error: %v", err)
+	}
+	ch.Close()
+}
+
+func TestPhoenixChannel_NetDialContext(t *testing.T) {
+	mock := newJoiningMockServer()
+	server := httptest.NewServer(http.HandlerFunc(mock.handler))
+	defer server.Close()
+
+	var dialed atomic.Value
+	ch := NewPhoenixTransport(Config{
+		// *.localhost is resolved to loopback before the custom dialer runs.
+		NodeURL:  "ws://node.localhost:" + server.URL[strings.LastIndex(server.URL, ":")+1:] + "/plugin_socket/websocket",
+		APIKey:   "test-key",
+		AgentDID: "did:web:test",
+		NetDialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			dialed.Store(addr)
+			return (&net.Dialer{}).DialContext(ctx, network, addr)
+		},
+	}).(*phoenixChannel)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := ch.Connect(ctx, nil); err != nil {
+		t.Fatalf("Connect() error: %v", err)
+	}
+	defer ch.Close()
+
+	if got, _ := dialed.Load().(string); !strings.HasPrefix(got, "127.0.0.1:") {
+		t.Errorf("custom dialer got addr %q, want 127.0.0.1:<port>", got)
+	}
+}
+
+func TestRestClient_ProxyURL(t *testing.T) {
+	var proxied atomic.Value
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// A forward proxy receives the absolute target URL.
+		proxied.Store(r.URL.String())
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer proxy.Close()
+
+	n, err := newNetConfig(Config{ProxyURL: proxy.URL})
+	if err != nil {
+		t.Fatalf("newNetConfig() error: %v", err)
+	}
+	rc := newRestClient("http://node.example.invalid", StaticCredentials("test-key"), n)
+
+	var result map[string]bool
+	if err := rc.get(context.Background(), "/api/v1/ping", &result); err != nil {
+		t.Fatalf("get() error: %v", err)
+	}
+	if got, _ := proxied.Load().(string); got != "http://node.example.invalid/api/v1/ping" {
+		t.Errorf("proxy saw %q, want request for the node URL", got)
+	}
+}
+
+func TestNewPhoenixTransport_InvalidProxyURL(t *testing.T) {
+	// A factory can call NewPhoenixTransport with a config resolveConfig never saw.
+	ch := NewPhoenixTransport(Config{
+		NodeURL:  "ws://node.localhost/plugin_socket/websocket",
+		APIKey:   "test-key",
+		AgentDID: "did:web:test",
+		ProxyURL: "ftp://proxy:21",
+	})
+	err := ch.Connect(context.Background(), nil)
+	if err == nil || !strings.Contains(err.Error(), "ProxyURL") {
+		t.Errorf("Connect() error = %v, want the ProxyURL error", err)
+	}
+}
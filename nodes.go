@@ -0,0 +1,161 @@
+package layr8
+
+import (
+	"math/rand/v2"
+	"sync"
+)
+
+// NodeSelection chooses which of several cloud-node URLs to connect to.
+type NodeSelection int
+
+const (
+	// SelectOrdered tries nodes in the configured order. After a failure it
+	// moves to the next node; a new outage starts again from the first node,
+	// so the agent returns to its primary as soon as it is reachable. (default)
+	SelectOrdered NodeSelection = iota
+
+	// SelectRandom picks a random node for each attempt, avoiding the node
+	// that just failed. Spreads a fleet of agents across nodes.
+	SelectRandom
+
+	// SelectHealthWeighted picks randomly, weighting each node by its recent
+	// connection failures so unhealthy nodes are tried less often.
+	SelectHealthWeighted
+)
+
+// nodePool tracks the configured node URLs and picks the next one to dial.
+type nodePool struct {
+	mu       sync.Mutex
+	urls     []string
+	strategy NodeSelection
+	failures []int // consecutive failed connection attempts per node
+	next     int   // SelectOrdered: index of the next candidate
+	last     int   // index of the last node picked or tried, -1 before the first
+}
+
+func newNodePool(urls []string, strategy NodeSelection) *nodePool {
+	return &nodePool{
+		urls:     urls,
+		strategy: strategy,
+		failures: make([]int, len(urls)),
+		last:     -1,
+	}
+}
+
+// pick returns the URL to try next.
+func (p *nodePool) pick() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	i := 0
+	switch {
+	case len(p.urls) == 1:
+	case p.strategy == SelectRandom && p.last < 0:
+		i = rand.IntN(len(p.urls))
+	case p.strategy == SelectRandom:
+		// Uniform over all nodes except the one that was just tried.
+		i = rand.IntN(len(p.urls) - 1)
+		if i >= p.last {
+			i++
+		}
+	case p.strategy == SelectHealthWeighted:
+		i = p.pickWeighted()
+	default:
+		i = p.next
+	}
+	p.last = i
+	return p.urls[i]
+}
+
+// order returns every node once, in the order the strategy would try them:
+// from the next candidate for SelectOrdered, a shuffle that puts the last
+// node picked at the end for SelectRandom, and a weighted draw without
+// replacement for SelectHealthWeighted.
+func (p *nodePool) order() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	n := len(p.urls)
+	idx := make([]int, n)
+	switch p.strategy {
+	case SelectRandom:
+		for i, j := range rand.Perm(n) {
+			idx[i] = j
+		}
+		if n > 1 && idx[0] == p.last {
+			idx[0], idx[n-1] = idx[n-1], idx[0]
+		}
+	case SelectHealthWeighted:
+		weights := p.weights()
+		for k := range idx {
+			i := weightedIndex(weights)
+			idx[k] = i
+			weights[i] = 0
+		}
+	default:
+		for k := range idx {
+			idx[k] = (p.next + k) % n
+		}
+	}
+	urls := make([]string, n)
+	for k, i := range idx {
+		urls[k] = p.urls[i]
+	}
+	return urls
+}
+
+// pickWeighted picks index i with weight 1/(1+failures[i])². Must be called with p.mu held.
+func (p *nodePool) pickWeighted() int {
+	return weightedIndex(p.weights())
+}
+
+// weights returns each node's selection weight, 1/(1+failures)². Must be called with p.mu held.
+func (p *nodePool) weights() []float64 {
+	weights := make([]float64, len(p.urls))
+	for i, f := range p.failures {
+		weights[i] = 1 / float64((1+f)*(1+f))
+	}
+	return weights
+}
+
+// weightedIndex picks index i with probability weights[i]/sum(weights).
+// At least one weight must be non-zero; zero weights are never picked.
+func weightedIndex(weights []float64) int {
+	var total float64
+	for _, w := range weights {
+		total += w
+	}
+	r := rand.Float64() * total
+	last := len(weights) - 1
+	for i, w := range weights {
+		if w == 0 {
+			continue
+		}
+		if r < w {
+			return i
+		}
+		r -= w
+		last = i
+	}
+	return last
+}
+
+// report records the outcome of a connection attempt to url.
+func (p *nodePool) report(url string, ok bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i, u := range p.urls {
+		if u != url {
+			continue
+		}
+		p.last = i
+		if ok {
+			p.failures[i] = 0
+			p.next = 0
+		} else {
+			p.failures[i]++
+			p.next = (i + 1) % len(p.urls)
+		}
+		return
+	}
+}
@@ -0,0 +1,195 @@
+package layr8
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNodePool_Ordered(t *testing.T) {
+	p := newNodePool([]string{"a", "b", "c"}, SelectOrdered)
+
+	if got := p.pick(); got != "a" {
+		t.Fatalf("first pick = %q, want a", got)
+	}
+	p.report("a", false)
+	if got := p.pick(); got != "b" {
+		t.Fatalf("pick after a failed = %q, want b", got)
+	}
+	p.report("b", false)
+	p.report("c", false)
+	if got := p.pick(); got != "a" {
+		t.Fatalf("pick after c failed = %q, want wrap-around to a", got)
+	}
+
+	// A later outage starts again from the primary.
+	p.report("b", true)
+	if got := p.pick(); got != "a" {
+		t.Errorf("pick after success on b = %q, want a", got)
+	}
+}
+
+func TestNodePool_RandomAvoidsLastNode(t *testing.T) {
+	p := newNodePool([]string{"a", "b"}, SelectRandom)
+	prev := p.pick()
+	for range 20 {
+		got := p.pick()
+		if got == prev {
+			t.Fatalf("picked %q twice in a row", got)
+		}
+		prev = got
+	}
+}
+
+func TestNodePool_HealthWeighted(t *testing.T) {
+	p := newNodePool([]string{"sick", "healthy"}, SelectHealthWeighted)
+	for range 4 {
+		p.report("sick", false)
+	}
+
+	counts := map[string]int{}
+	for range 1000 {
+		counts[p.pick()]++
+	}
+	// Weights are 1/25 vs 1: the sick node gets roughly 4% of picks.
+	if counts["sick"] > 100 || counts["healthy"] < 900 {
+		t.Errorf("picks = %v, want the healthy node strongly preferred", counts)
+	}
+}
+
+func TestNodePool_OrderTriesEachNodeOnce(t *testing.T) {
+	for _, strategy := range []NodeSelection{SelectOrdered, SelectRandom, SelectHealthWeighted} {
+		p := newNodePool([]string{"a", "b", "c", "d"}, strategy)
+		for range 3 {
+			p.report("c", false)
+		}
+		for range 50 {
+			got := p.order()
+			slices.Sort(got)
+			if want := []string{"a", "b", "c", "d"}; !slices.Equal(got, want) {
+				t.Fatalf("strategy %d: order() = %v, want each node once", strategy, got)
+			}
+		}
+	}
+
+	p := newNodePool([]string{"a", "b", "c"}, SelectOrdered)
+	p.report("a", false)
+	if got, want := p.order(), []string{"b", "c", "a"}; !slices.Equal(got, want) {
+		t.Errorf("order() after a failed = %v, want %v", got, want)
+	}
+}
+
+func TestPhoenixChannel_ConnectTriesEveryNode(t *testing.T) {
+	mock := newJoiningMockServer()
+	server := httptest.NewServer(http.HandlerFunc(mock.handler))
+	defer server.Close()
+
+	good := "ws" + strings.TrimPrefix(server.URL, "http") + "/plugin_socket/websocket"
+	dead1 := "ws://127.0.0.1:1/plugin_socket/websocket"
+	dead2 := "ws://127.0.0.1:2/plugin_socket/websocket"
+
+	// With only the last node excluded, a random pick could try the dead
+	// nodes three times between them and never reach the good one.
+	for _, strategy := range []NodeSelection{SelectRandom, SelectHealthWeighted} {
+		for range 20 {
+			ch := NewPhoenixTransport(Config{
+				NodeURLs:      []string{dead1, dead2, good},
+				NodeSelection: strategy,
+				APIKey:        "test-key",
+				AgentDID:      "did:web:test",
+			}).(*phoenixChannel)
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			err := ch.Connect(ctx, nil)
+			cancel()
+			if err != nil {
+				t.Fatalf("strategy %d: Connect() error: %v", strategy, err)
+			}
+			ch.Close()
+		}
+	}
+}
+
+func TestPhoenixChannel_ConnectFailsOverToNextNode(t *testing.T) {
+	mock := newJoiningMockServer()
+	server := httptest.NewServer(http.HandlerFunc(mock.handler))
+	defer server.Close()
+
+	good := "ws" + strings.TrimPrefix(server.URL, "http") + "/plugin_socket/websocket"
+	dead := "ws://127.0.0.1:1/plugin_socket/websocket"
+
+	ch := NewPhoenixTransport(Config{
+		NodeURL:  dead,
+		NodeURLs: []string{dead, good},
+		APIKey:   "test-key",
+		AgentDID: "did:web:test",
+	}).(*phoenixChannel)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := ch.Connect(ctx, nil); err != nil {
+		t.Fatalf("Connect() error: %v", err)
+	}
+	defer ch.Close()
+
+	if got := ch.NodeURL(); got != good {
+		t.Errorf("NodeURL() = %q, want %q", got, good)
+	}
+}
+
+func TestClient_ReconnectFailsOverToNextNode(t *testing.T) {
+	mockA := newJoiningMockServer()
+	serverA := httptest.NewServer(http.HandlerFunc(mockA.handler))
+	mockB := newJoiningMockServer()
+	serverB := httptest.NewServer(http.HandlerFunc(mockB.handler))
+	defer serverB.Close()
+
+	urlA := "ws" + strings.TrimPrefix(serverA.URL, "http") + "/plugin_socket/websocket"
+	urlB := "ws" + strings.TrimPrefix(serverB.URL, "http") + "/plugin_socket/websocket"
+
+	client, err := NewClient(Config{
+		NodeURLs:        []string{urlA, urlB},
+		APIKey:          "test-key",
+		AgentDID:        "did:web:test",
+		ReconnectPolicy: ReconnectPolicy{InitialDelay: 10 * time.Millisecond},
+	}, discardErrors)
+	if err != nil {
+		t.Fatalf("NewClient() error: %v", err)
+	}
+
+	reconnectedTo := make(chan string, 1)
+	client.OnReconnect(func() { reconnectedTo <- client.NodeURL() })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Connect(ctx); err != nil {
+		t.Fatalf("Connect() error: %v", err)
+	}
+	defer client.Close()
+
+	if got := client.NodeURL(); got != urlA {
+		t.Fatalf("NodeURL() = %q, want primary %q", got, urlA)
+	}
+
+	// Take node A down completely.
+	mockA.mu.Lock()
+	mockA.conn.Close()
+	mockA.mu.Unlock()
+	serverA.Close()
+
+	select {
+	case got := <-reconnectedTo:
+		if got != urlB {
+			t.Errorf("reconnected to %q, want %q", got, urlB)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for failover")
+	}
+
+	if got, want := client.rest.url(""), restURLFromWebSocket(urlB); got != want {
+		t.Errorf("REST base URL = %q, want %q", got, want)
+	}
+}
@@ -1,10 +1,18 @@
 package layr8
 
+import "time"
+
 // HandlerOption configures handler behavior.
 type HandlerOption func(*handlerOptions)
 
 type handlerOptions struct {
-	manualAck bool
+	manualAck    bool
+	concurrency  int
+	backpressure *Backpressure
+	ordering     Ordering
+	timeout      time.Duration
+	middleware   []Middleware
+	minors       []int
 }
 
 func handlerDefaults() handlerOptions {
@@ -21,11 +29,70 @@ func WithManualAck() HandlerOption {
 	}
 }
 
+// WithConcurrency limits how many instances of a handler run at once.
+// Messages arriving while n are running are subject to the handler's
+// backpressure policy (see WithBackpressure). The default is unlimited,
+// subject only to Config.MaxConcurrentHandlers.
+func WithConcurrency(n int) HandlerOption {
+	return func(o *handlerOptions) {
+		o.concurrency = n
+	}
+}
+
+// WithBackpressure sets what happens to messages that arrive while the
+// handler or the client is at its concurrency limit.
+// Default: Config.Backpressure.
+func WithBackpressure(b Backpressure) HandlerOption {
+	return func(o *handlerOptions) {
+		o.backpressure = &b
+	}
+}
+
+// WithOrdering processes messages that share a thread ID (OrderByThread) or
+// sender DID (OrderBySender) one at a time, in arrival order. Messages with
+// different keys still run in parallel. Messages waiting for their key are
+// queued and acknowledged when their handler starts, whatever the
+// backpressure policy. Default: OrderNone.
+func WithOrdering(o Ordering) HandlerOption {
+	return func(opts *handlerOptions) {
+		opts.ordering = o
+	}
+}
+
+// WithTimeout bounds each handler invocation: the context passed to a
+// HandlerFuncCtx is cancelled after d. Handlers registered with Handle
+// receive no context and are unaffected. Default: no timeout.
+func WithTimeout(d time.Duration) HandlerOption {
+	return func(o *handlerOptions) {
+		o.timeout = d
+	}
+}
+
+// WithMiddleware wraps this handler with middleware. It runs inside the
+// client-wide middleware added with Client.Use, in the order given.
+func WithMiddleware(middleware ...Middleware) HandlerOption {
+	return func(o *handlerOptions) {
+		o.middleware = append(o.middleware, middleware...)
+	}
+}
+
+// WithMinorVersions sets the minor versions a HandleProtocol major-version
+// range such as "https://layr8.io/protocols/echo/1.x" is registered for.
+// The cloud-node routes by exact protocol, so a range only receives the
+// minor versions it registers. Default: 0 only.
+func WithMinorVersions(minors ...int) HandlerOption {
+	return func(o *handlerOptions) {
+		o.minors = append([]int{}, minors...)
+	}
+}
+
 // RequestOption configures request behavior.
 type RequestOption func(*requestOptions)
 
 type requestOptions struct {
 	parentThreadID string
+	retries        int
+	backoff        time.Duration
 }
 
 func requestDefaults() requestOptions {
@@ -39,6 +106,23 @@ func WithParentThread(pthid string) RequestOption {
 	}
 }
 
+// WithRetry resends the request up to n more times when it may have been
+// lost: when sending fails because the connection dropped, when the
+// connection is restored while waiting for the response, or when no
+// response arrives within backoff. backoff doubles with every attempt; 0
+// resends only after a dropped connection. The last attempt waits for the
+// response until ctx is done.
+//
+// Every attempt resends the same message, with the same ID and thread ID, so
+// responders can recognize the duplicate (see Config.Deduplication). Errors
+// are returned as a *RequestError carrying the number of attempts.
+func WithRetry(n int, backoff time.Duration) RequestOption {
+	return func(o *requestOptions) {
+		o.retries = max(n, 0)
+		o.backoff = max(backoff, 0)
+	}
+}
+
 // SendOption configures send behavior.
 type SendOption func(*sendOptions)
 
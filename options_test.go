@@ -1,6 +1,9 @@
 package layr8
 
-import "testing"
+import (
+	"testing"
+	"time"
+)
 
 func TestWithManualAck(t *testing.T) {
 	opts := handlerDefaults()
@@ -25,6 +28,19 @@ func TestWithParentThread(t *testing.T) {
 	}
 }
 
+func TestWithRetry(t *testing.T) {
+	opts := requestDefaults()
+	WithRetry(3, time.Second)(&opts)
+	if opts.retries != 3 || opts.backoff != time.Second {
+		t.Errorf("retries = %d, backoff = %v; want 3, 1s", opts.retries, opts.backoff)
+	}
+
+	WithRetry(-1, -time.Second)(&opts)
+	if opts.retries != 0 || opts.backoff != 0 {
+		t.Errorf("negative values = %d, %v; want them clamped to 0", opts.retries, opts.backoff)
+	}
+}
+
 func TestRequestDefaults(t *testing.T) {
 	opts := requestDefaults()
 	if opts.parentThreadID != "" {
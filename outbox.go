@@ -0,0 +1,411 @@
+package layr8
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// OutboundQueue enables buffering of outbound messages while the client is
+// disconnected. Queued messages are flushed in order after the connection is
+// restored. Overflow and expiry are reported to the ErrorHandler.
+type OutboundQueue struct {
+	// Store holds queued messages. Default: NewMemoryOutboundStore(1000).
+	Store OutboundStore
+
+	// MaxAge drops messages that waited longer than this before they could be
+	// flushed. 0 means messages never expire.
+	MaxAge time.Duration
+}
+
+// QueuedMessage is an outbound DIDComm message waiting in an OutboundStore.
+type QueuedMessage struct {
+	ID         string    `json:"id"`
+	Payload    []byte    `json:"payload"` // serialized DIDComm message
+	EnqueuedAt time.Time `json:"enqueued_at"`
+}
+
+// OutboundStore holds queued outbound messages in FIFO order.
+// Implementations must be safe for concurrent use.
+type OutboundStore interface {
+	// Push appends a message. It returns ErrQueueFull when the store is at capacity.
+	Push(msg QueuedMessage) error
+
+	// Peek returns the oldest message without removing it.
+	// The boolean is false when the store is empty.
+	Peek() (QueuedMessage, bool, error)
+
+	// Pop removes the oldest message.
+	Pop() error
+
+	// Len returns the number of queued messages.
+	Len() int
+}
+
+// ErrQueueFull is returned by an OutboundStore that is at capacity.
+var ErrQueueFull = errors.New("outbound queue is full")
+
+// --- In-memory store ---
+
+type memoryOutboundStore struct {
+	mu       sync.Mutex
+	capacity int
+	msgs     []QueuedMessage
+}
+
+// NewMemoryOutboundStore returns an in-memory OutboundStore holding at most
+// capacity messages. A capacity <= 0 defaults to 1000.
+func NewMemoryOutboundStore(capacity int) OutboundStore {
+	if capacity <= 0 {
+		capacity = 1000
+	}
+	return &memoryOutboundStore{capacity: capacity}
+}
+
+func (s *memoryOutboundStore) Push(msg QueuedMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.msgs) >= s.capacity {
+		return ErrQueueFull
+	}
+	s.msgs = append(s.msgs, msg)
+	return nil
+}
+
+func (s *memoryOutboundStore) Peek() (QueuedMessage, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.msgs) == 0 {
+		return QueuedMessage{}, false, nil
+	}
+	return s.msgs[0], true, nil
+}
+
+func (s *memoryOutboundStore) Pop() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.msgs) > 0 {
+		s.msgs[0] = QueuedMessage{}
+		s.msgs = s.msgs[1:]
+	}
+	return nil
+}
+
+func (s *memoryOutboundStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.msgs)
+}
+
+// --- File store ---
+
+// fileOutboundStore is an append-only log: Push appends the message and Pop
+// appends a tombstone for the oldest one. The file is compacted once it
+// holds capacity lines that no longer matter.
+type fileOutboundStore struct {
+	memoryOutboundStore
+	file  jsonlFile
+	lines int // lines in the file, live or not
+}
+
+// outboxRecord is a line of the outbound store file: a queued message, or a
+// tombstone (Pop set to its ID) removing the oldest message.
+type outboxRecord struct {
+	*QueuedMessage
+	Pop string `json:"pop,omitempty"`
+}
+
+// NewFileOutboundStore returns an OutboundStore persisted as JSON lines at path,
+// holding at most capacity messages (<= 0 defaults to 1000). Messages already in
+// the file are loaded, so messages queued before a restart are flushed on the
+// next Connect. Push and Pop return once the change is synced to disk.
+func NewFileOutboundStore(path string, capacity int) (OutboundStore, error) {
+	if capacity <= 0 {
+		capacity = 1000
+	}
+	s := &fileOutboundStore{
+		memoryOutboundStore: memoryOutboundStore{capacity: capacity},
+		file:                jsonlFile{path: path, name: "outbound store"},
+	}
+	err := s.file.load(func(line []byte) error {
+		var rec outboxRecord
+		if err := json.Unmarshal(line, &rec); err != nil {
+			return err
+		}
+		s.lines++
+		switch {
+		case rec.Pop != "":
+			if len(s.msgs) > 0 {
+				s.msgs = s.msgs[1:]
+			}
+		case rec.QueuedMessage != nil:
+			s.msgs = append(s.msgs, *rec.QueuedMessage)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *fileOutboundStore) Push(msg QueuedMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.msgs) >= s.capacity {
+		return ErrQueueFull
+	}
+	if err := s.file.append(outboxRecord{QueuedMessage: &msg}); err != nil {
+		return err
+	}
+	s.msgs = append(s.msgs, msg)
+	s.lines++
+	return nil
+}
+
+func (s *fileOutboundStore) Pop() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.msgs) == 0 {
+		return nil
+	}
+	if err := s.file.append(outboxRecord{Pop: s.msgs[0].ID}); err != nil {
+		return err
+	}
+	s.msgs[0] = QueuedMessage{}
+	s.msgs = s.msgs[1:]
+	s.lines++
+	if s.lines-len(s.msgs) >= s.capacity {
+		// The tombstone is already durable; a failed compaction leaves the log
+		// valid and is retried on the next Pop.
+		s.compact()
+	}
+	return nil
+}
+
+// compact rewrites the file with only the queued messages. Must be called with s.mu held.
+func (s *fileOutboundStore) compact() error {
+	err := s.file.rewrite(func(enc *json.Encoder) error {
+		for i := range s.msgs {
+			if err := enc.Encode(outboxRecord{QueuedMessage: &s.msgs[i]}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	s.lines = len(s.msgs)
+	return nil
+}
+
+// --- Client integration ---
+
+// outbox buffers outbound messages while the transport is disconnected and
+// flushes them in order after reconnect.
+type outbox struct {
+	store  OutboundStore
+	maxAge time.Duration
+
+	mu       sync.Mutex // serializes queue-or-write decisions to preserve order
+	flushing bool
+	waiters  map[string]chan error // message ID → blocked Send/Request caller
+}
+
+func newOutbox(q OutboundQueue) *outbox {
+	store := q.Store
+	if store == nil {
+		store = NewMemoryOutboundStore(0)
+	}
+	return &outbox{
+		store:   store,
+		maxAge:  q.MaxAge,
+		waiters: make(map[string]chan error),
+	}
+}
+
+// writeMessage sends serialized DIDComm data without waiting for the server
+// reply, queueing it if the transport is disconnected or a flush is pending.
+func (c *Client) writeMessage(id string, data []byte) error {
+	if c.outbox == nil {
+		return c.fireAndForget(data)
+	}
+
+	o := c.outbox
+	o.mu.Lock()
+	if !o.flushing && o.store.Len() == 0 {
+		err := c.fireAndForget(data)
+		if !errors.Is(err, ErrNotConnected) {
+			o.mu.Unlock()
+			return err
+		}
+	}
+	err := o.enqueue(id, data, nil)
+	o.mu.Unlock()
+	if err != nil {
+		c.reportOverflow(id, err)
+	}
+	return err
+}
+
+// deliverMessage sends serialized DIDComm data and waits for the server reply.
+// If the transport is disconnected, the message is queued and the call waits
+// until it is flushed after reconnect or ctx expires. A message whose caller
+// gave up waiting is still sent when the queue is flushed.
+func (c *Client) deliverMessage(ctx context.Context, id string, data []byte) error {
+	if c.outbox == nil {
+		return c.sendAndCheck(ctx, data)
+	}
+
+	o := c.outbox
+	o.mu.Lock()
+	queued := o.flushing || o.store.Len() > 0
+	o.mu.Unlock()
+
+	if !queued {
+		err := c.sendAndCheck(ctx, data)
+		if !errors.Is(err, ErrNotConnected) {
+			return err
+		}
+	}
+
+	done := make(chan error, 1)
+	o.mu.Lock()
+	err := o.enqueue(id, data, done)
+	o.mu.Unlock()
+	if err != nil {
+		c.reportOverflow(id, err)
+		return err
+	}
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// enqueue pushes a message into the store. Must be called with o.mu held.
+func (o *outbox) enqueue(id string, data []byte, done chan error) error {
+	if err := o.store.Push(QueuedMessage{ID: id, Payload: data, EnqueuedAt: time.Now()}); err != nil {
+		return err
+	}
+	if done != nil {
+		o.waiters[id] = done
+	}
+	return nil
+}
+
+func (c *Client) reportOverflow(id string, err error) {
+	c.onError(SDKError{
+		Kind:      ErrQueueOverflow,
+		MessageID: id,
+		Cause:     err,
+		Timestamp: time.Now(),
+	})
+}
+
+// fireAndForget sends data without waiting for the server reply.
+func (c *Client) fireAndForget(data []byte) error {
+	t := c.currentTransport()
+	if t == nil {
+		return ErrNotConnected
+	}
+	return t.SendFireAndForget("message", data)
+}
+
+// sendAndCheck sends data and converts a server rejection into an error.
+func (c *Client) sendAndCheck(ctx context.Context, data []byte) error {
+	t := c.currentTransport()
+	if t == nil {
+		return ErrNotConnected
+	}
+	reply, err := t.Send(ctx, "message", data)
+	if err != nil {
+		return err
+	}
+	if reply.Status == "error" {
+		return fmt.Errorf("server rejected message: %s", reply.Reason)
+	}
+	return nil
+}
+
+// flushOutbox sends queued messages in order. It stops early if the transport
+// disconnects again; the remaining messages go out on the next reconnect.
+func (c *Client) flushOutbox() {
+	o := c.outbox
+	o.mu.Lock()
+	if o.flushing {
+		o.mu.Unlock()
+		return
+	}
+	o.flushing = true
+	o.mu.Unlock()
+
+	for {
+		o.mu.Lock()
+		msg, ok, err := o.store.Peek()
+		if err != nil || !ok {
+			o.flushing = false
+			o.mu.Unlock()
+			if err != nil {
+				c.onError(SDKError{Kind: ErrTransportWrite, Cause: fmt.Errorf("read outbound queue: %w", err), Timestamp: time.Now()})
+			}
+			return
+		}
+		done := o.waiters[msg.ID]
+		o.mu.Unlock()
+
+		if o.maxAge > 0 && time.Since(msg.EnqueuedAt) > o.maxAge {
+			c.completeQueued(msg.ID, done, fmt.Errorf("queued message expired after %s", o.maxAge))
+			c.onError(SDKError{
+				Kind:      ErrQueueExpired,
+				MessageID: msg.ID,
+				Cause:     fmt.Errorf("queued for %s, max age %s", time.Since(msg.EnqueuedAt).Round(time.Millisecond), o.maxAge),
+				Timestamp: time.Now(),
+			})
+			continue
+		}
+
+		if done != nil {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			err = c.sendAndCheck(ctx, msg.Payload)
+			cancel()
+		} else {
+			err = c.fireAndForget(msg.Payload)
+		}
+
+		if errors.Is(err, ErrNotConnected) {
+			o.mu.Lock()
+			o.flushing = false
+			o.mu.Unlock()
+			return
+		}
+		if err != nil && done == nil {
+			c.onError(SDKError{Kind: ErrTransportWrite, MessageID: msg.ID, Cause: err, Timestamp: time.Now()})
+		}
+		c.completeQueued(msg.ID, done, err)
+	}
+}
+
+// completeQueued removes the head message and reports the result to its waiter, if any.
+func (c *Client) completeQueued(id string, done chan error, result error) {
+	o := c.outbox
+	o.mu.Lock()
+	popErr := o.store.Pop()
+	delete(o.waiters, id)
+	o.mu.Unlock()
+
+	if popErr != nil {
+		c.onError(SDKError{Kind: ErrTransportWrite, MessageID: id, Cause: fmt.Errorf("remove from outbound queue: %w", popErr), Timestamp: time.Now()})
+	}
+
+	if done != nil {
+		done <- result
+	}
+}
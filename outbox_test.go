@@ -0,0 +1,284 @@
+package layr8
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMemoryOutboundStore_FIFOAndCapacity(t *testing.T) {
+	s := NewMemoryOutboundStore(2)
+
+	if err := s.Push(QueuedMessage{ID: "1"}); err != nil {
+		t.Fatalf("Push() error: %v", err)
+	}
+	if err := s.Push(QueuedMessage{ID: "2"}); err != nil {
+		t.Fatalf("Push() error: %v", err)
+	}
+	if err := s.Push(QueuedMessage{ID: "3"}); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("Push() over capacity = %v, want ErrQueueFull", err)
+	}
+
+	msg, ok, _ := s.Peek()
+	if !ok || msg.ID != "1" {
+		t.Fatalf("Peek() = %q, %v; want %q, true", msg.ID, ok, "1")
+	}
+	s.Pop()
+	msg, _, _ = s.Peek()
+	if msg.ID != "2" {
+		t.Errorf("Peek() after Pop = %q, want %q", msg.ID, "2")
+	}
+	s.Pop()
+	if _, ok, _ := s.Peek(); ok || s.Len() != 0 {
+		t.Errorf("store should be empty, Len() = %d", s.Len())
+	}
+}
+
+func TestFileOutboundStore_Persists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "outbox.jsonl")
+
+	s, err := NewFileOutboundStore(path, 0)
+	if err != nil {
+		t.Fatalf("NewFileOutboundStore() error: %v", err)
+	}
+	for _, id := range []string{"1", "2", "3"} {
+		if err := s.Push(QueuedMessage{ID: id, Payload: []byte(`{"id":"` + id + `"}`), EnqueuedAt: time.Now()}); err != nil {
+			t.Fatalf("Push() error: %v", err)
+		}
+	}
+	if err := s.Pop(); err != nil {
+		t.Fatalf("Pop() error: %v", err)
+	}
+
+	// Reopen, as after a process restart.
+	s, err = NewFileOutboundStore(path, 0)
+	if err != nil {
+		t.Fatalf("reopen error: %v", err)
+	}
+	if s.Len() != 2 {
+		t.Fatalf("Len() after reopen = %d, want 2", s.Len())
+	}
+	msg, _, _ := s.Peek()
+	if msg.ID != "2" || string(msg.Payload) != `{"id":"2"}` {
+		t.Errorf("Peek() = %+v, want message 2", msg)
+	}
+}
+
+func TestFileOutboundStore_CompactsTombstones(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "outbox.jsonl")
+	s, err := NewFileOutboundStore(path, 3)
+	if err != nil {
+		t.Fatalf("NewFileOutboundStore() error: %v", err)
+	}
+	s.Push(QueuedMessage{ID: "first", Payload: []byte(`{}`)})
+	for i := range 20 {
+		s.Push(QueuedMessage{ID: fmt.Sprint(i), Payload: []byte(`{}`)})
+		if i > 0 {
+			s.Pop()
+		}
+	}
+	// After the first push, each push is matched by a pop: 18 and 19 remain.
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error: %v", err)
+	}
+	if lines := bytes.Count(data, []byte("\n")); lines > 2*3 {
+		t.Errorf("file has %d lines, want it compacted to at most 6", lines)
+	}
+	s, err = NewFileOutboundStore(path, 3)
+	if err != nil {
+		t.Fatalf("reopen error: %v", err)
+	}
+	if msg, _, _ := s.Peek(); s.Len() != 2 || msg.ID != "18" {
+		t.Errorf("after reopen Len() = %d, Peek() = %q, want 2 messages starting at 18", s.Len(), msg.ID)
+	}
+}
+
+func (f *fakeTransport) setDown(down bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.down = down
+}
+
+func (f *fakeTransport) sentIDs() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var ids []string
+	for _, payload := range f.sent {
+		var env struct {
+			ID string `json:"id"`
+		}
+		json.Unmarshal(payload, &env)
+		ids = append(ids, env.ID)
+	}
+	return ids
+}
+
+func waitForSent(t *testing.T, fake *fakeTransport, n int) []string {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if ids := fake.sentIDs(); len(ids) >= n {
+			return ids
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("sent %v, want %d messages", fake.sentIDs(), n)
+	return nil
+}
+
+func TestClient_OutboundQueue_FlushesInOrderOnReconnect(t *testing.T) {
+	fake := &fakeTransport{}
+	client := newTestClient(t, fake, Config{OutboundQueue: &OutboundQueue{}}, nil)
+	connectTestClient(t, client)
+	fake.setDown(true)
+
+	for _, id := range []string{"m1", "m2", "m3"} {
+		err := client.Send(context.Background(), &Message{ID: id, Type: "https://didcomm.org/basicmessage/2.0/message", To: []string{"did:web:bob"}}, WithFireAndForget())
+		if err != nil {
+			t.Fatalf("Send(%s) while disconnected error: %v", id, err)
+		}
+	}
+	if n := len(fake.sentIDs()); n != 0 {
+		t.Fatalf("sent %d messages while disconnected, want 0", n)
+	}
+
+	fake.setDown(false)
+	fake.reconnect()
+
+	ids := waitForSent(t, fake, 3)
+	for i, want := range []string{"m1", "m2", "m3"} {
+		if ids[i] != want {
+			t.Errorf("sent[%d] = %q, want %q", i, ids[i], want)
+		}
+	}
+}
+
+func TestClient_OutboundQueue_BlockingSendWaitsForFlush(t *testing.T) {
+	fake := &fakeTransport{}
+	client := newTestClient(t, fake, Config{OutboundQueue: &OutboundQueue{}}, nil)
+	connectTestClient(t, client)
+	fake.setDown(true)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- client.Send(context.Background(), &Message{ID: "m1", Type: "https://didcomm.org/basicmessage/2.0/message", To: []string{"did:web:bob"}})
+	}()
+
+	select {
+	case err := <-done:
+		t.Fatalf("Send() returned %v before reconnect", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	fake.setDown(false)
+	fake.reconnect()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Send() error: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Send() did not return after flush")
+	}
+}
+
+func TestClient_OutboundQueue_Overflow(t *testing.T) {
+	fake := &fakeTransport{}
+	var mu sync.Mutex
+	var kinds []ErrorKind
+	client := newTestClient(t, fake, Config{OutboundQueue: &OutboundQueue{Store: NewMemoryOutboundStore(1)}}, func(e SDKError) {
+		mu.Lock()
+		kinds = append(kinds, e.Kind)
+		mu.Unlock()
+	})
+	connectTestClient(t, client)
+	fake.setDown(true)
+
+	msg := func(id string) *Message {
+		return &Message{ID: id, Type: "https://didcomm.org/basicmessage/2.0/message", To: []string{"did:web:bob"}}
+	}
+	if err := client.Send(context.Background(), msg("m1"), WithFireAndForget()); err != nil {
+		t.Fatalf("Send(m1) error: %v", err)
+	}
+	if err := client.Send(context.Background(), msg("m2"), WithFireAndForget()); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("Send(m2) = %v, want ErrQueueFull", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(kinds) != 1 || kinds[0] != ErrQueueOverflow {
+		t.Errorf("reported kinds = %v, want [ErrQueueOverflow]", kinds)
+	}
+}
+
+func TestClient_OutboundQueue_DropsExpired(t *testing.T) {
+	fake := &fakeTransport{}
+	expired := make(chan string, 1)
+	client := newTestClient(t, fake, Config{OutboundQueue: &OutboundQueue{MaxAge: 20 * time.Millisecond}}, func(e SDKError) {
+		if e.Kind == ErrQueueExpired {
+			expired <- e.MessageID
+		}
+	})
+	connectTestClient(t, client)
+	fake.setDown(true)
+
+	client.Send(context.Background(), &Message{ID: "old", Type: "https://didcomm.org/basicmessage/2.0/message", To: []string{"did:web:bob"}}, WithFireAndForget())
+	time.Sleep(50 * time.Millisecond)
+	client.Send(context.Background(), &Message{ID: "fresh", Type: "https://didcomm.org/basicmessage/2.0/message", To: []string{"did:web:bob"}}, WithFireAndForget())
+
+	fake.setDown(false)
+	fake.reconnect()
+
+	select {
+	case id := <-expired:
+		if id != "old" {
+			t.Errorf("expired message = %q, want %q", id, "old")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected ErrQueueExpired")
+	}
+	ids := waitForSent(t, fake, 1)
+	if len(ids) != 1 || ids[0] != "fresh" {
+		t.Errorf("sent = %v, want [fresh]", ids)
+	}
+}
+
+func TestClient_OutboundQueue_FlushesPersistedOnConnect(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "outbox.jsonl")
+	store, err := NewFileOutboundStore(path, 0)
+	if err != nil {
+		t.Fatalf("NewFileOutboundStore() error: %v", err)
+	}
+	store.Push(QueuedMessage{ID: "persisted", Payload: []byte(`{"id":"persisted"}`), EnqueuedAt: time.Now()})
+
+	fake := &fakeTransport{}
+	client := newTestClient(t, fake, Config{OutboundQueue: &OutboundQueue{Store: store}}, nil)
+	connectTestClient(t, client)
+
+	ids := waitForSent(t, fake, 1)
+	if ids[0] != "persisted" {
+		t.Errorf("sent = %v, want [persisted]", ids)
+	}
+}
+
+func TestClient_NoOutboundQueue_ReturnsErrNotConnected(t *testing.T) {
+	fake := &fakeTransport{}
+	client := newTestClient(t, fake, Config{}, nil)
+	connectTestClient(t, client)
+	fake.setDown(true)
+
+	err := client.Send(context.Background(), &Message{Type: "https://didcomm.org/basicmessage/2.0/message", To: []string{"did:web:bob"}})
+	if !errors.Is(err, ErrNotConnected) {
+		t.Errorf("Send() = %v, want ErrNotConnected", err)
+	}
+}
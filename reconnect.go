@@ -1,34 +1,160 @@
 package layr8
 
-import "time"
+import (
+	"fmt"
+	"math/rand/v2"
+	"time"
+)
 
-// backoff implements exponential backoff with a maximum delay.
+// Jitter selects how randomness is applied to reconnect delays.
+// Jitter spreads out reconnects so that many agents dropped by the same
+// node restart do not all reconnect in lockstep.
+type Jitter int
+
+const (
+	NoJitter           Jitter = iota // deterministic exponential delays (default)
+	FullJitter                       // uniform random delay between 0 and the exponential delay
+	DecorrelatedJitter               // random delay between InitialDelay and 3× the previous delay
+)
+
+// ReconnectPolicy controls how a dropped connection is re-established.
+// The zero value reconnects forever with exponential backoff from 1s to 30s
+// and no jitter.
+type ReconnectPolicy struct {
+	// InitialDelay is the delay before the first reconnect attempt. Default: 1s.
+	InitialDelay time.Duration
+
+	// MaxDelay caps the delay between attempts. Default: 30s.
+	MaxDelay time.Duration
+
+	// Multiplier grows the delay after each failed attempt. Default: 2.
+	Multiplier float64
+
+	// Jitter randomizes delays. Default: NoJitter.
+	Jitter Jitter
+
+	// MaxAttempts gives up after this many failed attempts. 0 means unlimited.
+	MaxAttempts int
+
+	// MaxElapsed gives up once this much time has passed since the connection
+	// dropped. 0 means unlimited.
+	MaxElapsed time.Duration
+
+	// StableAfter is how long a connection must stay up before the backoff
+	// delay and the attempt/elapsed counters reset. A connection that drops
+	// sooner continues the previous backoff, so a flapping node is not
+	// hammered at InitialDelay. Default: 1m.
+	StableAfter time.Duration
+
+	// OnGiveUp is called once when MaxAttempts or MaxElapsed is exhausted.
+	// The error wraps ErrReconnectGaveUp. After giving up the transport stays
+	// disconnected and operations return ErrNotConnected.
+	OnGiveUp func(err error)
+}
+
+func (p ReconnectPolicy) withDefaults() ReconnectPolicy {
+	if p.InitialDelay == 0 {
+		p.InitialDelay = 1 * time.Second
+	}
+	if p.MaxDelay == 0 {
+		p.MaxDelay = 30 * time.Second
+	}
+	if p.Multiplier == 0 {
+		p.Multiplier = 2
+	}
+	if p.StableAfter == 0 {
+		p.StableAfter = 1 * time.Minute
+	}
+	return p
+}
+
+func (p ReconnectPolicy) validate() error {
+	if p.InitialDelay < 0 || p.MaxDelay < 0 || p.MaxElapsed < 0 || p.StableAfter < 0 {
+		return fmt.Errorf("ReconnectPolicy durations must not be negative")
+	}
+	if p.Multiplier != 0 && p.Multiplier < 1 {
+		return fmt.Errorf("ReconnectPolicy.Multiplier must be >= 1, got %v", p.Multiplier)
+	}
+	if p.MaxAttempts < 0 {
+		return fmt.Errorf("ReconnectPolicy.MaxAttempts must not be negative")
+	}
+	if p.Jitter < NoJitter || p.Jitter > DecorrelatedJitter {
+		return fmt.Errorf("ReconnectPolicy.Jitter: unknown value %d", p.Jitter)
+	}
+	return nil
+}
+
+// exhausted reports whether the policy's limits have been reached for an
+// outage that started at start and has seen attempts failed attempts.
+func (p ReconnectPolicy) exhausted(attempts int, start time.Time) bool {
+	if p.MaxAttempts > 0 && attempts >= p.MaxAttempts {
+		return true
+	}
+	return p.MaxElapsed > 0 && time.Since(start) >= p.MaxElapsed
+}
+
+func (p ReconnectPolicy) newBackoff() *backoff {
+	b := newBackoff(p.InitialDelay, p.MaxDelay)
+	b.multiplier = p.Multiplier
+	b.jitter = p.Jitter
+	return b
+}
+
+// backoff implements exponential backoff with a maximum delay and optional jitter.
 type backoff struct {
-	initial time.Duration
-	max     time.Duration
-	current time.Duration
+	initial    time.Duration
+	max        time.Duration
+	current    time.Duration
+	multiplier float64
+	jitter     Jitter
+	prev       time.Duration // last returned delay, for DecorrelatedJitter
 }
 
 func newBackoff(initial, max time.Duration) *backoff {
 	return &backoff{
-		initial: initial,
-		max:     max,
-		current: initial,
+		initial:    initial,
+		max:        max,
+		current:    initial,
+		multiplier: 2,
+		prev:       initial,
 	}
 }
 
 func (b *backoff) next() time.Duration {
+	if b.jitter == DecorrelatedJitter {
+		// See "Exponential Backoff And Jitter" (AWS Architecture Blog):
+		// sleep = min(max, random_between(initial, prev * 3))
+		d := b.initial + randDuration(b.prev*3-b.initial)
+		if d > b.max {
+			d = b.max
+		}
+		b.prev = d
+		return d
+	}
+
 	d := b.current
-	b.current *= 2
+	b.current = time.Duration(float64(b.current) * b.multiplier)
 	if b.current > b.max {
 		b.current = b.max
 	}
 	if d > b.max {
 		d = b.max
 	}
+	if b.jitter == FullJitter {
+		d = randDuration(d)
+	}
 	return d
 }
 
 func (b *backoff) reset() {
 	b.current = b.initial
+	b.prev = b.initial
+}
+
+// randDuration returns a uniformly random duration in [0, d].
+func randDuration(d time.Duration) time.Duration {
+	if d <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int64N(int64(d) + 1))
 }
@@ -58,3 +58,89 @@ func TestBackoff_Reset(t *testing.T) {
 		t.Errorf("after reset, backoff = %v, want 1s", d)
 	}
 }
+
+func TestBackoff_Multiplier(t *testing.T) {
+	b := ReconnectPolicy{InitialDelay: 100 * time.Millisecond, MaxDelay: time.Second, Multiplier: 3}.withDefaults().newBackoff()
+
+	want := []time.Duration{100 * time.Millisecond, 300 * time.Millisecond, 900 * time.Millisecond, time.Second}
+	for i, w := range want {
+		if d := b.next(); d != w {
+			t.Errorf("backoff #%d = %v, want %v", i+1, d, w)
+		}
+	}
+}
+
+func TestBackoff_FullJitter(t *testing.T) {
+	b := ReconnectPolicy{Jitter: FullJitter}.withDefaults().newBackoff()
+
+	ceilings := []time.Duration{1 * time.Second, 2 * time.Second, 4 * time.Second}
+	for i, ceiling := range ceilings {
+		if d := b.next(); d < 0 || d > ceiling {
+			t.Errorf("jittered backoff #%d = %v, want within [0, %v]", i+1, d, ceiling)
+		}
+	}
+}
+
+func TestBackoff_DecorrelatedJitter(t *testing.T) {
+	b := ReconnectPolicy{Jitter: DecorrelatedJitter, MaxDelay: 10 * time.Second}.withDefaults().newBackoff()
+
+	prev := 1 * time.Second
+	for i := 0; i < 20; i++ {
+		d := b.next()
+		upper := min(prev*3, 10*time.Second)
+		if d < 1*time.Second || d > upper {
+			t.Fatalf("decorrelated backoff #%d = %v, want within [1s, %v]", i+1, d, upper)
+		}
+		prev = d
+	}
+
+	b.reset()
+	if d := b.next(); d > 3*time.Second {
+		t.Errorf("after reset, backoff = %v, want <= 3s", d)
+	}
+}
+
+func TestReconnectPolicy_Defaults(t *testing.T) {
+	p := ReconnectPolicy{}.withDefaults()
+	if p.InitialDelay != time.Second || p.MaxDelay != 30*time.Second || p.Multiplier != 2 || p.StableAfter != time.Minute {
+		t.Errorf("defaults = %+v, want 1s/30s/x2/1m", p)
+	}
+	if p.MaxAttempts != 0 || p.MaxElapsed != 0 {
+		t.Error("default policy should retry forever")
+	}
+}
+
+func TestReconnectPolicy_Validate(t *testing.T) {
+	invalid := []ReconnectPolicy{
+		{InitialDelay: -time.Second},
+		{Multiplier: 0.5},
+		{MaxAttempts: -1},
+		{Jitter: Jitter(42)},
+	}
+	for _, p := range invalid {
+		if err := p.validate(); err == nil {
+			t.Errorf("validate(%+v) should fail", p)
+		}
+	}
+	if err := (ReconnectPolicy{Jitter: FullJitter, MaxAttempts: 5}).validate(); err != nil {
+		t.Errorf("validate() error: %v", err)
+	}
+}
+
+func TestReconnectPolicy_Exhausted(t *testing.T) {
+	p := ReconnectPolicy{MaxAttempts: 3}
+	if p.exhausted(2, time.Now()) {
+		t.Error("2 of 3 attempts should not be exhausted")
+	}
+	if !p.exhausted(3, time.Now()) {
+		t.Error("3 of 3 attempts should be exhausted")
+	}
+
+	p = ReconnectPolicy{MaxElapsed: time.Minute}
+	if p.exhausted(100, time.Now()) {
+		t.Error("elapsed limit should ignore attempt count")
+	}
+	if !p.exhausted(0, time.Now().Add(-2*time.Minute)) {
+		t.Error("outage older than MaxElapsed should be exhausted")
+	}
+}
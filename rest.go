@@ -6,38 +6,45 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"net"
 	"net/http"
+	"sync"
 	"time"
 )
 
 // restClient is the internal HTTP client for the cloud-node REST API.
-// It handles JSON serialization, API key auth, and localhost resolution.
+// It handles JSON serialization, API key auth, and shares TLS, proxy and
+// localhost resolution settings with the Phoenix channel (see netConfig).
 type restClient struct {
-	baseURL    string
-	apiKey     string
-	httpClient *http.Client
+	mu          sync.Mutex
+	baseURL     string // follows the active node on failover
+	credentials CredentialProvider
+	httpClient  *http.Client
 }
 
-func newRestClient(baseURL, apiKey string) *restClient {
+func newRestClient(baseURL string, credentials CredentialProvider, n netConfig) *restClient {
 	return &restClient{
-		baseURL: baseURL,
-		apiKey:  apiKey,
+		baseURL:     baseURL,
+		credentials: credentials,
 		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-			Transport: &http.Transport{
-				DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
-					host, port, err := net.SplitHostPort(addr)
-					if err == nil && isLocalhost(host) {
-						addr = net.JoinHostPort("127.0.0.1", port)
-					}
-					return (&net.Dialer{}).DialContext(ctx, network, addr)
-				},
-			},
+			Timeout:   30 * time.Second,
+			Transport: n.httpTransport(),
 		},
 	}
 }
 
+// setBaseURL points the client at a different node.
+func (r *restClient) setBaseURL(baseURL string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.baseURL = baseURL
+}
+
+func (r *restClient) url(path string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.baseURL + path
+}
+
 // post sends a JSON POST request and decodes the response into result.
 func (r *restClient) post(ctx context.Context, path string, body any, result any) error {
 	data, err := json.Marshal(body)
@@ -45,32 +52,34 @@ func (r *restClient) post(ctx context.Context, path string, body any, result any
 		return fmt.Errorf("marshal request body: %w", err)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.baseURL+path, bytes.NewReader(data))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.url(path), bytes.NewReader(data))
 	if err != nil {
 		return fmt.Errorf("create request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
-	if r.apiKey != "" {
-		req.Header.Set("x-api-key", r.apiKey)
-	}
 
 	return r.do(req, result)
 }
 
 // get sends a GET request and decodes the response into result.
 func (r *restClient) get(ctx context.Context, path string, result any) error {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.baseURL+path, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.url(path), nil)
 	if err != nil {
 		return fmt.Errorf("create request: %w", err)
 	}
-	if r.apiKey != "" {
-		req.Header.Set("x-api-key", r.apiKey)
-	}
 
 	return r.do(req, result)
 }
 
 func (r *restClient) do(req *http.Request, result any) error {
+	apiKey, err := r.credentials.APIKey(req.Context())
+	if err != nil {
+		return fmt.Errorf("get API key: %w", err)
+	}
+	if apiKey != "" {
+		req.Header.Set("x-api-key", apiKey)
+	}
+
 	resp, err := r.httpClient.Do(req)
 	if err != nil {
 		return fmt.Errorf("REST request failed: %w", err)
@@ -0,0 +1,111 @@
+package layr8
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Shutdown gracefully stops the client. It stops handling new inbound
+// messages (they stay unacknowledged, so the cloud-node redelivers them later),
+// waits for running handlers and pending Requests to finish, flushes the
+// outbound queue, and then closes the connection like Close, which sends any
+// batched acks.
+//
+// If ctx expires first, Shutdown closes the connection anyway and returns
+// ctx.Err(). Responses from handlers still running at that point are lost.
+func (c *Client) Shutdown(ctx context.Context) error {
+	if !c.setState(StateDraining, nil, StateJoined, StateReconnecting) {
+		// Never connected, already given up, or already closing.
+		if c.State() == StateDraining {
+			return c.waitClosed(ctx)
+		}
+		return c.Close()
+	}
+
+	err := c.inflight.wait(ctx)
+	if err == nil {
+		err = c.drainOutbox(ctx)
+	}
+
+	if closeErr := c.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// waitClosed waits for a concurrent Shutdown to finish.
+func (c *Client) waitClosed(ctx context.Context) error {
+	changes := c.WatchState(ctx)
+	for c.State() != StateClosed {
+		select {
+		case <-changes:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// drainOutbox waits until queued outbound messages have been flushed.
+func (c *Client) drainOutbox(ctx context.Context) error {
+	if c.outbox == nil {
+		return nil
+	}
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	for c.outbox.store.Len() > 0 {
+		go c.flushOutbox() // no-op if a flush is already running
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// inflight counts running handlers and outstanding Requests so Shutdown can
+// wait for them. Unlike sync.WaitGroup, add may be called while wait is blocked.
+type inflight struct {
+	mu   sync.Mutex
+	n    int
+	idle chan struct{} // closed when n drops to zero; nil when nobody waits
+}
+
+func (f *inflight) add() {
+	f.mu.Lock()
+	f.n++
+	f.mu.Unlock()
+}
+
+func (f *inflight) done() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.n--
+	if f.n == 0 && f.idle != nil {
+		close(f.idle)
+		f.idle = nil
+	}
+}
+
+// wait blocks until the count reaches zero or ctx is done.
+func (f *inflight) wait(ctx context.Context) error {
+	f.mu.Lock()
+	if f.n == 0 {
+		f.mu.Unlock()
+		return nil
+	}
+	if f.idle == nil {
+		f.idle = make(chan struct{})
+	}
+	idle := f.idle
+	f.mu.Unlock()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
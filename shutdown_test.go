@@ -0,0 +1,149 @@
+package layr8
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+const shutdownTestType = "https://layr8.io/protocols/echo/1.0/request"
+
+func TestClient_Shutdown_DrainsInFlightHandlers(t *testing.T) {
+	fake := &fakeTransport{}
+	started := make(chan string, 2)
+	release := make(chan struct{})
+	client := newTestClient(t, fake, Config{}, nil)
+	client.Handle(shutdownTestType, func(msg *Message) (*Message, error) {
+		started <- msg.ID
+		<-release
+		return &Message{Type: "https://layr8.io/protocols/echo/1.0/response", Body: map[string]string{}}, nil
+	})
+	connectTestClient(t, client)
+
+	fake.handler(inboundPayload("in-1", shutdownTestType, ""))
+	if id := <-started; id != "in-1" {
+		t.Fatalf("started %q, want in-1", id)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- client.Shutdown(context.Background()) }()
+	waitForState(t, client, StateDraining)
+
+	// New inbound messages are neither handled nor acknowledged while draining.
+	fake.handler(inboundPayload("in-2", shutdownTestType, ""))
+	select {
+	case id := <-started:
+		t.Fatalf("handler started for %q while draining", id)
+	case <-time.After(50 * time.Millisecond):
+	}
+	select {
+	case err := <-done:
+		t.Fatalf("Shutdown() returned %v while a handler was running", err)
+	default:
+	}
+
+	close(release)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Shutdown() error: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Shutdown() did not return after the handler finished")
+	}
+
+	if len(fake.sentIDs()) != 1 {
+		t.Errorf("sent %d messages, want the in-flight handler's response", len(fake.sentIDs()))
+	}
+	for _, id := range fake.ackedIDs() {
+		if id == "in-2" {
+			t.Error("message received while draining should not be acked")
+		}
+	}
+	if client.State() != StateClosed || !fake.closed {
+		t.Errorf("State() = %s, transport closed = %v; want Closed, true", client.State(), fake.closed)
+	}
+}
+
+func TestClient_Shutdown_WaitsForPendingRequest(t *testing.T) {
+	fake := &fakeTransport{}
+	client := newTestClient(t, fake, Config{}, nil)
+	client.Handle(shutdownTestType, func(msg *Message) (*Message, error) { return nil, nil })
+	connectTestClient(t, client)
+
+	result := make(chan error, 1)
+	go func() {
+		_, err := client.Request(context.Background(), &Message{
+			Type:     shutdownTestType,
+			To:       []string{"did:web:bob"},
+			ThreadID: "thread-1",
+		})
+		result <- err
+	}()
+	waitForSent(t, fake, 1)
+
+	done := make(chan error, 1)
+	go func() { done <- client.Shutdown(context.Background()) }()
+	waitForState(t, client, StateDraining)
+
+	// The response to a pending Request is still delivered while draining.
+	fake.handler(inboundPayload("resp-1", "https://layr8.io/protocols/echo/1.0/response", "thread-1"))
+
+	if err := <-result; err != nil {
+		t.Errorf("Request() error: %v", err)
+	}
+	if err := <-done; err != nil {
+		t.Errorf("Shutdown() error: %v", err)
+	}
+}
+
+func TestClient_Shutdown_ContextExpires(t *testing.T) {
+	fake := &fakeTransport{}
+	release := make(chan struct{})
+	defer close(release)
+	started := make(chan struct{})
+	client := newTestClient(t, fake, Config{}, nil)
+	client.Handle(shutdownTestType, func(msg *Message) (*Message, error) {
+		close(started)
+		<-release
+		return nil, nil
+	})
+	connectTestClient(t, client)
+
+	fake.handler(inboundPayload("in-1", shutdownTestType, ""))
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := client.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Shutdown() = %v, want context.DeadlineExceeded", err)
+	}
+	if client.State() != StateClosed {
+		t.Errorf("State() = %s, want Closed", client.State())
+	}
+}
+
+func TestClient_Shutdown_NotConnected(t *testing.T) {
+	client, _ := NewClient(Config{
+		NodeURL: "ws://localhost:4000/plugin_socket/websocket",
+		APIKey:  "test-key",
+	}, discardErrors)
+	if err := client.Shutdown(context.Background()); err != nil {
+		t.Errorf("Shutdown() before Connect = %v, want nil", err)
+	}
+	if client.State() != StateClosed {
+		t.Errorf("State() = %s, want Closed", client.State())
+	}
+}
+
+func waitForState(t *testing.T, client *Client, want ConnectionState) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for client.State() != want {
+		if time.Now().After(deadline) {
+			t.Fatalf("State() = %s, want %s", client.State(), want)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
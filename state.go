@@ -0,0 +1,161 @@
+package layr8
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+)
+
+// ConnectionState is the connection lifecycle state of a Client.
+//
+// Connect moves Idle to Connecting, then to Joined (or back to Idle if it
+// fails). A dropped connection moves Joined to Reconnecting, which returns to
+// Joined once restored, or to Idle if the ReconnectPolicy gives up. Shutdown
+// moves Joined or Reconnecting to Draining. Close moves any state to Closed,
+// which is final.
+type ConnectionState int
+
+const (
+	StateIdle         ConnectionState = iota // not connected; Connect may be called
+	StateConnecting                          // Connect in progress
+	StateJoined                              // connected and joined to the channel
+	StateReconnecting                        // connection dropped, reconnecting
+	StateDraining                            // Shutdown in progress; no new inbound messages are handled
+	StateClosed                              // Close called; the client cannot be reused
+)
+
+var connectionStateNames = map[ConnectionState]string{
+	StateIdle:         "Idle",
+	StateConnecting:   "Connecting",
+	StateJoined:       "Joined",
+	StateReconnecting: "Reconnecting",
+	StateDraining:     "Draining",
+	StateClosed:       "Closed",
+}
+
+func (s ConnectionState) String() string {
+	if name, ok := connectionStateNames[s]; ok {
+		return name
+	}
+	return "Unknown"
+}
+
+// StateChange describes a connection state transition.
+type StateChange struct {
+	From    ConnectionState
+	To      ConnectionState
+	Reason  error  // why the transition happened, if known (e.g. the disconnect error)
+	NodeURL string // node the client is connected to or was last connected to
+	At      time.Time
+}
+
+// connState tracks the current state and fans transitions out to watchers.
+type connState struct {
+	mu       sync.Mutex
+	state    ConnectionState
+	watchers map[*stateWatcher]struct{}
+}
+
+type stateWatcher struct {
+	mu     sync.Mutex
+	queue  []StateChange
+	notify chan struct{}
+}
+
+// State returns the current connection state.
+func (c *Client) State() ConnectionState {
+	c.state.mu.Lock()
+	defer c.state.mu.Unlock()
+	return c.state.state
+}
+
+// WatchState returns a channel that receives every connection state transition
+// from now on, in order. Slow readers do not block the client: transitions are
+// buffered until read. The channel is closed when ctx is done or after the
+// transition to StateClosed has been delivered.
+func (c *Client) WatchState(ctx context.Context) <-chan StateChange {
+	w := &stateWatcher{notify: make(chan struct{}, 1)}
+	out := make(chan StateChange)
+
+	c.state.mu.Lock()
+	closed := c.state.state == StateClosed
+	if !closed {
+		if c.state.watchers == nil {
+			c.state.watchers = make(map[*stateWatcher]struct{})
+		}
+		c.state.watchers[w] = struct{}{}
+	}
+	c.state.mu.Unlock()
+
+	if closed {
+		close(out)
+		return out
+	}
+
+	go func() {
+		defer close(out)
+		defer func() {
+			c.state.mu.Lock()
+			delete(c.state.watchers, w)
+			c.state.mu.Unlock()
+		}()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-w.notify:
+			}
+
+			w.mu.Lock()
+			pending := w.queue
+			w.queue = nil
+			w.mu.Unlock()
+
+			for _, change := range pending {
+				select {
+				case out <- change:
+				case <-ctx.Done():
+					return
+				}
+				if change.To == StateClosed {
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// setState moves to the given state and notifies watchers. If from is
+// non-empty, the transition only happens when the current state is one of
+// them. It reports whether the state changed.
+// Must not be called with c.mu held.
+func (c *Client) setState(to ConnectionState, reason error, from ...ConnectionState) bool {
+	nodeURL := c.NodeURL()
+
+	c.state.mu.Lock()
+	defer c.state.mu.Unlock()
+
+	cur := c.state.state
+	if cur == to || cur == StateClosed {
+		return false
+	}
+	if len(from) > 0 && !slices.Contains(from, cur) {
+		return false
+	}
+	c.state.state = to
+
+	change := StateChange{From: cur, To: to, Reason: reason, NodeURL: nodeURL, At: time.Now()}
+	for w := range c.state.watchers {
+		w.mu.Lock()
+		w.queue = append(w.queue, change)
+		w.mu.Unlock()
+		select {
+		case w.notify <- struct{}{}:
+		default:
+		}
+	}
+	return true
+}
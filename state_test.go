@@ -0,0 +1,286 @@
+package layr8
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestConnectionState_String(t *testing.T) {
+	tests := []struct {
+		state ConnectionState
+		want  string
+	}{
+		{StateIdle, "Idle"},
+		{StateConnecting, "Connecting"},
+		{StateJoined, "Joined"},
+		{StateReconnecting, "Reconnecting"},
+		{StateDraining, "Draining"},
+		{StateClosed, "Closed"},
+		{ConnectionState(99), "Unknown"},
+	}
+	for _, tt := range tests {
+		if got := tt.state.String(); got != tt.want {
+			t.Errorf("ConnectionState(%d).String() = %q, want %q", tt.state, got, tt.want)
+		}
+	}
+}
+
+func expectState(t *testing.T, changes <-chan StateChange, from, to ConnectionState) StateChange {
+	t.Helper()
+	select {
+	case c, ok := <-changes:
+		if !ok {
+			t.Fatalf("state channel closed, want %s → %s", from, to)
+		}
+		if c.From != from || c.To != to {
+			t.Fatalf("transition = %s → %s, want %s → %s", c.From, c.To, from, to)
+		}
+		return c
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timeout waiting for %s → %s", from, to)
+	}
+	return StateChange{}
+}
+
+func TestClient_StateLifecycle(t *testing.T) {
+	mock, _, wsURL := setupMockServer(t)
+
+	client, err := NewClient(Config{
+		NodeURL:         wsURL,
+		APIKey:          "test-key",
+		AgentDID:        "did:web:test",
+		ReconnectPolicy: ReconnectPolicy{InitialDelay: 10 * time.Millisecond},
+	}, discardErrors)
+	if err != nil {
+		t.Fatalf("NewClient() error: %v", err)
+	}
+	if client.State() != StateIdle {
+		t.Fatalf("State() = %s, want Idle", client.State())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	changes := client.WatchState(ctx)
+
+	if err := client.Connect(ctx); err != nil {
+		t.Fatalf("Connect() error: %v", err)
+	}
+	expectState(t, changes, StateIdle, StateConnecting)
+	joined := expectState(t, changes, StateConnecting, StateJoined)
+	if joined.NodeURL != wsURL {
+		t.Errorf("NodeURL = %q, want %q", joined.NodeURL, wsURL)
+	}
+	if client.State() != StateJoined {
+		t.Errorf("State() = %s, want Joined", client.State())
+	}
+
+	mock.mu.Lock()
+	mock.conn.Close()
+	mock.mu.Unlock()
+
+	dropped := expectState(t, changes, StateJoined, StateReconnecting)
+	if dropped.Reason == nil {
+		t.Error("Reconnecting transition should carry the disconnect error")
+	}
+	expectState(t, changes, StateReconnecting, StateJoined)
+
+	client.Close()
+	expectState(t, changes, StateJoined, StateClosed)
+	if _, ok := <-changes; ok {
+		t.Error("state channel should be closed after StateClosed")
+	}
+	if err := client.Connect(ctx); !errors.Is(err, ErrClientClosed) {
+		t.Errorf("Connect() after Close = %v, want ErrClientClosed", err)
+	}
+}
+
+func TestClient_StateConnectFailureReturnsToIdle(t *testing.T) {
+	client, err := NewClient(Config{
+		NodeURL:  "ws://127.0.0.1:1/plugin_socket/websocket",
+		APIKey:   "test-key",
+		AgentDID: "did:web:test",
+	}, discardErrors)
+	if err != nil {
+		t.Fatalf("NewClient() error: %v", err)
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	changes := client.WatchState(ctx)
+
+	if err := client.Connect(ctx); err == nil {
+		t.Fatal("Connect() should fail")
+	}
+	expectState(t, changes, StateIdle, StateConnecting)
+	failed := expectState(t, changes, StateConnecting, StateIdle)
+	if failed.Reason == nil {
+		t.Error("Idle transition should carry the connect error")
+	}
+
+	// Connect may be retried from Idle.
+	if err := client.Connect(ctx); errors.Is(err, ErrAlreadyConnected) {
+		t.Error("Connect() from Idle should be allowed")
+	}
+}
+
+func TestClient_StateGiveUpReturnsToIdle(t *testing.T) {
+	mock, server, wsURL := setupMockServer(t)
+
+	var mu sync.Mutex
+	var gaveUp error
+	client, err := NewClient(Config{
+		NodeURL:  wsURL,
+		APIKey:   "test-key",
+		AgentDID: "did:web:test",
+		ReconnectPolicy: ReconnectPolicy{
+			InitialDelay: 10 * time.Millisecond,
+			MaxAttempts:  1,
+			OnGiveUp: func(err error) {
+				mu.Lock()
+				gaveUp = err
+				mu.Unlock()
+			},
+		},
+	}, discardErrors)
+	if err != nil {
+		t.Fatalf("NewClient() error: %v", err)
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := client.Connect(ctx); err != nil {
+		t.Fatalf("Connect() error: %v", err)
+	}
+	changes := client.WatchState(ctx)
+
+	mock.mu.Lock()
+	mock.conn.Close()
+	mock.mu.Unlock()
+	server.Close()
+
+	expectState(t, changes, StateJoined, StateReconnecting)
+	idle := expectState(t, changes, StateReconnecting, StateIdle)
+	if !errors.Is(idle.Reason, ErrReconnectGaveUp) {
+		t.Errorf("Reason = %v, want ErrReconnectGaveUp", idle.Reason)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if !errors.Is(gaveUp, ErrReconnectGaveUp) {
+		t.Errorf("user OnGiveUp got %v, want ErrReconnectGaveUp", gaveUp)
+	}
+	if err := client.Send(ctx, &Message{Type: "https://didcomm.org/basicmessage/2.0/message", To: []string{"did:web:bob"}}); !errors.Is(err, ErrNotConnected) {
+		t.Errorf("Send() after give-up = %v, want ErrNotConnected", err)
+	}
+}
+
+func TestPhoenixChannel_JoinRejectedDoesNotReconnect(t *testing.T) {
+	var mu sync.Mutex
+	joins := 0
+
+	mock := newMockServer()
+	mock.onMsg = func(msg phoenixMessage) {
+		if msg.Event == "phx_join" {
+			mu.Lock()
+			joins++
+			mu.Unlock()
+			mock.sendToClient(phoenixMessage{
+				JoinRef: msg.Ref, Ref: msg.Ref, Topic: msg.Topic, Event: "phx_reply",
+				Payload: json.RawMessage(`{"status":"error","response":{"reason":"unauthorized"}}`),
+			})
+		}
+	}
+	server := httptest.NewServer(http.HandlerFunc(mock.handler))
+	defer server.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/plugin_socket/websocket"
+	ch := newPhoenixChannel(wsURL, "test-key", "did:web:test")
+	ch.reconnectPolicy.InitialDelay = 10 * time.Millisecond
+	disconnected := make(chan error, 1)
+	ch.OnDisconnect(func(err error) { disconnected <- err })
+	defer ch.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := ch.Connect(ctx, nil); err == nil {
+		t.Fatal("Connect() should fail when the join is rejected")
+	}
+
+	select {
+	case err := <-disconnected:
+		t.Fatalf("OnDisconnect(%v) fired after a failed Connect", err)
+	case <-time.After(200 * time.Millisecond):
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if joins != 1 {
+		t.Errorf("joins = %d, want 1 (no background reconnect)", joins)
+	}
+}
+
+// unreachableTransport is a fakeTransport whose Connect always fails.
+type unreachableTransport struct{ *fakeTransport }
+
+func (unreachableTransport) Connect(ctx context.Context, protocols []string) error {
+	return &ConnectionError{URL: "ws://fake.invalid", Reason: "connection refused"}
+}
+
+func TestClient_GiveUp_FailedConnectKeepsHandlersSafe(t *testing.T) {
+	first := &fakeTransport{}
+	var giveUp func(error)
+	transports := []Transport{first, unreachableTransport{&fakeTransport{}}}
+	client, err := NewClient(Config{
+		NodeURL:  "ws://localhost:4000/plugin_socket/websocket",
+		APIKey:   "test-key",
+		AgentDID: "did:web:test:alice",
+		Transport: func(cfg Config) Transport {
+			giveUp = cfg.ReconnectPolicy.OnGiveUp
+			t := transports[0]
+			transports = transports[1:]
+			return t
+		},
+	}, discardErrors)
+	if err != nil {
+		t.Fatalf("NewClient() error: %v", err)
+	}
+	defer client.Close()
+
+	h := newBlockingHandler()
+	client.Handle(dispatchTypeA, func(msg *Message) (*Message, error) {
+		h.fn(msg)
+		return &Message{Type: dispatchTypeB}, nil
+	})
+	connectTestClient(t, client)
+
+	first.handler(inboundPayload("m1", dispatchTypeA, ""))
+	h.expectStart(t, "m1")
+
+	// The reconnect policy gives up, and reconnecting by hand fails too.
+	first.drop(errors.New("connection reset"))
+	giveUp(ErrReconnectGaveUp)
+	if err := client.Connect(context.Background()); err == nil {
+		t.Fatal("Connect() should fail")
+	}
+
+	// The running handler's response must not crash the client.
+	h.release <- struct{}{}
+	deadline := time.Now().Add(2 * time.Second)
+	for client.HandlerStats()[dispatchTypeA].Running > 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if got := client.HandlerStats()[dispatchTypeA].Running; got != 0 {
+		t.Fatalf("Running = %d, want the handler finished", got)
+	}
+	if err := client.Send(context.Background(), &Message{Type: dispatchTypeA, To: []string{"did:web:bob"}}); !errors.Is(err, ErrNotConnected) {
+		t.Errorf("Send() = %v, want ErrNotConnected", err)
+	}
+}
@@ -476,15 +476,13 @@ func main() {
 	// ═══════════════════════════════════════════════════════════════════
 
 	// Test 12: ErrAlreadyConnected
-	fmt.Println("  [12] Handle after Connect → ErrAlreadyConnected")
+	fmt.Println("  [12] Connect when connected → ErrAlreadyConnected")
 
-	err = alice.Handle(echoRequest, func(msg *layr8.Message) (*layr8.Message, error) {
-		return nil, nil
-	})
+	err = alice.Connect(ctx)
 	if errors.Is(err, layr8.ErrAlreadyConnected) {
-		pass("Handle after Connect returns ErrAlreadyConnected")
+		pass("Connect when connected returns ErrAlreadyConnected")
 	} else if err != nil {
-		pass(fmt.Sprintf("Handle after Connect returns error: %v", err))
+		pass(fmt.Sprintf("Connect when connected returns error: %v", err))
 	} else {
 		fail("ErrAlreadyConnected", "expected error, got nil")
 	}
@@ -2,42 +2,82 @@ package layr8
 
 import "context"
 
-// serverReply is the parsed Phoenix phx_reply for a sent message.
-type serverReply struct {
+// ServerReply is the cloud-node's reply to a message sent with Transport.Send.
+type ServerReply struct {
 	Status string // "ok" or "error"
 	Reason string // server's rejection reason (e.g., "unauthorized")
 }
 
-// transport is the internal interface for communication with the cloud-node.
-// The current implementation uses WebSocket/Phoenix Channel (channel.go).
-// Future implementations may use QUIC (quic.go).
-type transport interface {
-	// connect establishes the connection and joins the channel with the given protocols.
-	connect(ctx context.Context, protocols []string) error
+// Transport is the interface for communication with the cloud-node.
+// The default implementation uses WebSocket/Phoenix Channels (see NewPhoenixTransport),
+// which is the only protocol cloud-nodes currently expose.
+// Supply a different implementation via Config.Transport — for example an
+// in-memory transport for tests, or a wrapper that adds instrumentation.
+//
+// Implementations must be safe for concurrent use: Send, SendFireAndForget and
+// SendAck are called from handler goroutines as well as from caller goroutines.
+type Transport interface {
+	// Connect establishes the connection and joins the channel with the given protocols.
+	Connect(ctx context.Context, protocols []string) error
 
-	// send writes a raw Phoenix Channel message and waits for the server's phx_reply.
+	// UpdateProtocols replaces the protocols registered with the cloud-node on
+	// a live connection, without dropping messages in flight. While
+	// disconnected it only records them for the next (re)join.
+	UpdateProtocols(ctx context.Context, protocols []string) error
+
+	// Send writes a message and waits for the server's reply.
 	// The context controls the timeout for waiting on the reply.
-	send(ctx context.Context, event string, payload []byte) (serverReply, error)
+	// It returns ErrNotConnected if the message could not be written, and
+	// ErrConnectionLost if the connection dropped before the reply arrived.
+	Send(ctx context.Context, event string, payload []byte) (ServerReply, error)
+
+	// SendFireAndForget writes a message without waiting for a reply.
+	SendFireAndForget(event string, payload []byte) error
+
+	// SendAck acknowledges message IDs to the cloud-node.
+	SendAck(ids []string) error
 
-	// sendFireAndForget writes a raw Phoenix Channel message without waiting for a reply.
-	sendFireAndForget(event string, payload []byte) error
+	// SetMessageHandler registers the callback for inbound "message" events.
+	// The callback receives the raw payload bytes (context + plaintext envelope).
+	SetMessageHandler(fn func(payload []byte))
 
-	// sendAck acknowledges message IDs to the cloud-node.
-	sendAck(ids []string) error
+	// Close gracefully shuts down the connection.
+	Close() error
 
-	// setMessageHandler registers the callback for inbound "message" events.
-	// The callback receives the raw payload bytes.
-	setMessageHandler(fn func(payload []byte))
+	// OnDisconnect registers a callback for when the connection drops.
+	OnDisconnect(fn func(error))
 
-	// close gracefully shuts down the connection.
-	close() error
+	// OnReconnect registers a callback for when the connection is restored.
+	OnReconnect(fn func())
 
-	// onDisconnect registers a callback for when the connection drops.
-	onDisconnect(fn func(error))
+	// AssignedDID returns the DID assigned by the cloud-node on join (for ephemeral DIDs).
+	AssignedDID() string
+
+	// NodeURL returns the URL of the cloud-node the transport is connected to.
+	// With several configured nodes it changes when a reconnect fails over.
+	NodeURL() string
+}
 
-	// onReconnect registers a callback for when the connection is restored.
-	onReconnect(fn func())
+// TransportFactory creates the Transport used by a Client on Connect.
+// It receives the resolved client configuration.
+type TransportFactory func(cfg Config) Transport
 
-	// assignedDID returns the DID assigned by the cloud-node on join (for ephemeral DIDs).
-	assignedDID() string
+// NewPhoenixTransport returns the default WebSocket/Phoenix Channel transport
+// for the given configuration. It can be wrapped by a custom TransportFactory
+// to add behavior around the default transport.
+// Invalid network settings (such as an unsupported ProxyURL) are reported by
+// Connect.
+func NewPhoenixTransport(cfg Config) Transport {
+	ch := newPhoenixChannel(cfg.NodeURL, cfg.APIKey, cfg.AgentDID)
+	ch.reconnectPolicy = cfg.ReconnectPolicy.withDefaults()
+	ch.heartbeat = cfg.Heartbeat.withDefaults()
+	ch.net, ch.netErr = newNetConfig(cfg)
+	ch.apiKeyPlacement = cfg.APIKeyPlacement
+	if len(cfg.NodeURLs) > 0 {
+		ch.nodes = newNodePool(cfg.NodeURLs, cfg.NodeSelection)
+	}
+	if cfg.Credentials != nil {
+		ch.credentials = cfg.Credentials
+	}
+	return ch
 }
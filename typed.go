@@ -0,0 +1,58 @@
+package layr8
+
+import (
+	"context"
+	"fmt"
+)
+
+// TypedHandlerFunc is a handler that receives the inbound message body
+// already decoded into Req. msg is still available for metadata such as
+// From, ThreadID and Context, and for Ack when using WithManualAck.
+type TypedHandlerFunc[Req, Resp any] func(ctx context.Context, msg *Message, req Req) (Resp, error)
+
+// HandleTyped registers a handler whose message body is decoded into Req.
+// A body that fails to decode is answered with an "e.p.msg.bad-request"
+// problem report without calling fn; with WithManualAck it is acknowledged
+// first, since a redelivery would fail the same way. On success, the
+// returned Resp is sent as the body of a respType reply; if respType is
+// empty, the Resp is discarded and no reply is sent.
+//
+// Otherwise HandleTyped behaves like Client.HandleContext.
+func HandleTyped[Req, Resp any](c *Client, msgType, respType string, fn TypedHandlerFunc[Req, Resp], opts ...HandlerOption) error {
+	return c.HandleContext(msgType, func(ctx context.Context, msg *Message) (*Message, error) {
+		var req Req
+		if err := msg.UnmarshalBody(&req); err != nil {
+			msg.Ack() // no-op unless manual ack
+			return nil, &ProblemReportError{
+				Code:    "e.p.msg.bad-request",
+				Comment: "invalid {1} body: {2}",
+				Args:    []string{msg.Type, err.Error()},
+			}
+		}
+
+		resp, err := fn(ctx, msg, req)
+		if err != nil || respType == "" {
+			return nil, err
+		}
+		return &Message{Type: respType, Body: resp}, nil
+	}, opts...)
+}
+
+// RequestTyped sends req as the body of a msgType message to the given DID
+// and decodes the response body into Resp. Errors are those of Client.Request,
+// plus a decode error if the response body doesn't fit Resp.
+func RequestTyped[Req, Resp any](ctx context.Context, c *Client, msgType, to string, req Req, opts ...RequestOption) (Resp, error) {
+	var out Resp
+	resp, err := c.Request(ctx, &Message{
+		Type: msgType,
+		To:   []string{to},
+		Body: req,
+	}, opts...)
+	if err != nil {
+		return out, err
+	}
+	if err := resp.UnmarshalBody(&out); err != nil {
+		return out, fmt.Errorf("decode %s response: %w", resp.Type, err)
+	}
+	return out, nil
+}
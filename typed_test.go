@@ -0,0 +1,165 @@
+package layr8
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+type typedEchoRequest struct {
+	Message string `json:"message"`
+}
+
+type typedEchoResponse struct {
+	Echo string `json:"echo"`
+}
+
+const typedResponseType = "https://layr8.io/protocols/jobs/1.0/a-response"
+
+func lastSent(t *testing.T, fake *fakeTransport, n int) map[string]json.RawMessage {
+	t.Helper()
+	waitForSent(t, fake, n)
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	var out map[string]json.RawMessage
+	json.Unmarshal(fake.sent[n-1], &out)
+	return out
+}
+
+// answerRequest replies to the first message the client sends, on its thread,
+// with a body built from the request body.
+func answerRequest(fake *fakeTransport, reply func(reqBody json.RawMessage) any) {
+	for len(fake.sentIDs()) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	fake.mu.Lock()
+	var sent struct {
+		Thid string          `json:"thid"`
+		Body json.RawMessage `json:"body"`
+	}
+	json.Unmarshal(fake.sent[0], &sent)
+	fake.mu.Unlock()
+	fake.handler(inboundPayloadWithBody("resp-1", typedResponseType, sent.Thid, reply(sent.Body)))
+}
+
+func TestHandleTyped(t *testing.T) {
+	fake := &fakeTransport{}
+	client := newTestClient(t, fake, Config{}, nil)
+	err := HandleTyped(client, dispatchTypeA, typedResponseType,
+		func(ctx context.Context, msg *Message, req typedEchoRequest) (typedEchoResponse, error) {
+			return typedEchoResponse{Echo: req.Message + " from " + msg.From}, nil
+		})
+	if err != nil {
+		t.Fatalf("HandleTyped() error: %v", err)
+	}
+	connectTestClient(t, client)
+
+	fake.handler(inboundPayloadWithBody("m1", dispatchTypeA, "", map[string]string{"message": "hi"}))
+	sent := lastSent(t, fake, 1)
+
+	var msgType, thid string
+	var body typedEchoResponse
+	json.Unmarshal(sent["type"], &msgType)
+	json.Unmarshal(sent["thid"], &thid)
+	json.Unmarshal(sent["body"], &body)
+	if msgType != typedResponseType || thid != "m1" || body.Echo != "hi from did:web:bob" {
+		t.Errorf("reply = type %q thid %q body %+v", msgType, thid, body)
+	}
+}
+
+func TestHandleTyped_DecodeFailure(t *testing.T) {
+	fake := &fakeTransport{}
+	client := newTestClient(t, fake, Config{}, nil)
+	called := false
+	HandleTyped(client, dispatchTypeA, typedResponseType,
+		func(ctx context.Context, msg *Message, req typedEchoRequest) (typedEchoResponse, error) {
+			called = true
+			return typedEchoResponse{}, nil
+		})
+	connectTestClient(t, client)
+
+	fake.handler(inboundPayloadWithBody("m1", dispatchTypeA, "", map[string]int{"message": 42}))
+	prob := sentProblem(t, fake)
+	if prob.Code != "e.p.msg.bad-request" || len(prob.Args) != 2 || prob.Args[0] != dispatchTypeA {
+		t.Errorf("problem report = %+v, want e.p.msg.bad-request naming the message type", prob)
+	}
+	if called {
+		t.Error("handler should not run when the body fails to decode")
+	}
+}
+
+func TestHandleTyped_DecodeFailure_ManualAck(t *testing.T) {
+	fake := &fakeTransport{}
+	client := newTestClient(t, fake, Config{}, nil)
+	HandleTyped(client, dispatchTypeA, typedResponseType,
+		func(ctx context.Context, msg *Message, req typedEchoRequest) (typedEchoResponse, error) {
+			msg.Ack()
+			return typedEchoResponse{}, nil
+		}, WithManualAck())
+	connectTestClient(t, client)
+
+	// The body can never decode, so the message is acked rather than redelivered.
+	fake.handler(inboundPayloadWithBody("m1", dispatchTypeA, "", map[string]int{"message": 42}))
+	if prob := sentProblem(t, fake); prob.Code != "e.p.msg.bad-request" {
+		t.Errorf("problem report code = %q, want e.p.msg.bad-request", prob.Code)
+	}
+	waitForAcks(t, fake, "m1")
+}
+
+func TestHandleTyped_NoResponseType(t *testing.T) {
+	fake := &fakeTransport{}
+	client := newTestClient(t, fake, Config{}, nil)
+	done := make(chan struct{})
+	HandleTyped(client, dispatchTypeA, "",
+		func(ctx context.Context, msg *Message, req typedEchoRequest) (struct{}, error) {
+			close(done)
+			return struct{}{}, nil
+		})
+	connectTestClient(t, client)
+
+	fake.handler(inboundPayloadWithBody("m1", dispatchTypeA, "", map[string]string{"message": "hi"}))
+	<-done
+	time.Sleep(20 * time.Millisecond)
+	if n := len(fake.sentIDs()); n != 0 {
+		t.Errorf("sent %d messages, want none without a response type", n)
+	}
+}
+
+func TestRequestTyped(t *testing.T) {
+	fake := &fakeTransport{}
+	client := newTestClient(t, fake, Config{}, nil)
+	connectTestClient(t, client)
+
+	go answerRequest(fake, func(body json.RawMessage) any {
+		var req typedEchoRequest
+		json.Unmarshal(body, &req)
+		return typedEchoResponse{Echo: req.Message}
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	resp, err := RequestTyped[typedEchoRequest, typedEchoResponse](ctx, client, dispatchTypeA, "did:web:bob", typedEchoRequest{Message: "hello"})
+	if err != nil {
+		t.Fatalf("RequestTyped() error: %v", err)
+	}
+	if resp.Echo != "hello" {
+		t.Errorf("Echo = %q, want hello", resp.Echo)
+	}
+}
+
+func TestRequestTyped_DecodeFailure(t *testing.T) {
+	fake := &fakeTransport{}
+	client := newTestClient(t, fake, Config{}, nil)
+	connectTestClient(t, client)
+
+	go answerRequest(fake, func(json.RawMessage) any { return map[string]int{"echo": 42} })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	_, err := RequestTyped[typedEchoRequest, typedEchoResponse](ctx, client, dispatchTypeA, "did:web:bob", typedEchoRequest{})
+	if err == nil || !strings.Contains(err.Error(), "decode") {
+		t.Errorf("RequestTyped() = %v, want a decode error", err)
+	}
+}
@@ -0,0 +1,110 @@
+package layr8
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// UnhandledPolicy selects what happens to inbound messages that cannot be
+// parsed or that no handler matches. Either way the error is also reported
+// to the ErrorHandler as ErrParseFailure or ErrNoHandler.
+type UnhandledPolicy int
+
+const (
+	// UnhandledLeave leaves the message unacknowledged, so the cloud-node
+	// redelivers it — for example once a handler is registered. (default)
+	UnhandledLeave UnhandledPolicy = iota
+
+	// UnhandledAckDrop acknowledges and discards the message.
+	UnhandledAckDrop
+
+	// UnhandledAckReply acknowledges the message and replies to the sender
+	// with an "e.p.msg.unsupported" problem report.
+	UnhandledAckReply
+
+	// UnhandledDeadLetter records the message in Config.DeadLetters and then
+	// acknowledges it.
+	UnhandledDeadLetter
+)
+
+// handleUnhandled applies Config.Unhandled to a message that failed to parse
+// or has no handler. For parse failures msg holds whatever could be salvaged
+// from the payload; a message without an ID cannot be acknowledged.
+func (c *Client) handleUnhandled(payload []byte, msg *Message, kind ErrorKind, cause error) {
+	switch c.cfg.Unhandled {
+	case UnhandledAckDrop:
+		c.ackUnhandled(msg)
+
+	case UnhandledAckReply:
+		c.ackUnhandled(msg)
+		if msg.From == "" {
+			return // nobody to reply to
+		}
+		prob := &ProblemReportError{
+			Code:    "e.p.msg.unsupported",
+			Comment: "message type {1} is not supported",
+			Args:    []string{msg.Type},
+		}
+		if kind == ErrParseFailure {
+			prob.Comment = "message could not be parsed: {1}"
+			prob.Args = []string{cause.Error()}
+		}
+		c.replyProblem(msg, prob)
+
+	case UnhandledDeadLetter:
+		dl := DeadLetter{
+			MessageID: msg.ID,
+			Type:      msg.Type,
+			From:      msg.From,
+			Kind:      kind,
+			Payload:   payload,
+			At:        time.Now(),
+		}
+		if cause != nil {
+			dl.Error = cause.Error()
+		}
+		if err := c.cfg.DeadLetters.Put(c.ctx, dl); err != nil {
+			c.onError(SDKError{
+				Kind:      ErrDeadLetter,
+				MessageID: msg.ID,
+				Type:      msg.Type,
+				From:      msg.From,
+				Cause:     err,
+				Timestamp: time.Now(),
+			})
+			return // left unacked for redelivery
+		}
+		c.ackUnhandled(msg)
+	}
+}
+
+func (c *Client) ackUnhandled(msg *Message) {
+	if msg.ID != "" {
+		c.ack(msg.ID)
+	}
+}
+
+// salvageMessage extracts the routing fields of a payload that parseDIDComm
+// rejected, so it can still be acknowledged or answered.
+func salvageMessage(payload []byte) *Message {
+	var env struct {
+		Plaintext struct {
+			ID   json.RawMessage `json:"id"`
+			Type json.RawMessage `json:"type"`
+			From json.RawMessage `json:"from"`
+			ThID json.RawMessage `json:"thid"`
+		} `json:"plaintext"`
+	}
+	json.Unmarshal(payload, &env)
+	str := func(raw json.RawMessage) string {
+		var s string
+		json.Unmarshal(raw, &s)
+		return s
+	}
+	return &Message{
+		ID:       str(env.Plaintext.ID),
+		Type:     str(env.Plaintext.Type),
+		From:     str(env.Plaintext.From),
+		ThreadID: str(env.Plaintext.ThID),
+	}
+}
@@ -0,0 +1,212 @@
+package layr8
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+const unhandledType = "https://layr8.io/protocols/unknown/1.0/ping"
+
+// failingSink is a DeadLetterSink whose Put always fails.
+type failingSink struct {
+	DeadLetterSink
+	err error
+}
+
+func (s failingSink) Put(ctx context.Context, dl DeadLetter) error { return s.err }
+
+// connectUnhandledClient connects a client without handlers to the mock node
+// and returns the errors reported to its ErrorHandler.
+func connectUnhandledClient(t *testing.T, cfg Config) (*mockPhoenixServer, chan SDKError) {
+	t.Helper()
+	mock, _, wsURL := setupMockServer(t)
+	errs := make(chan SDKError, 10)
+	cfg.NodeURL = wsURL
+	cfg.APIKey = "test-key"
+	cfg.AgentDID = "did:web:alice"
+	client, err := NewClient(cfg, func(err SDKError) { errs <- err })
+	if err != nil {
+		t.Fatalf("NewClient() error: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Connect(ctx); err != nil {
+		t.Fatalf("Connect() error: %v", err)
+	}
+	return mock, errs
+}
+
+func sendInbound(mock *mockPhoenixServer, plaintext map[string]any) {
+	payload, _ := json.Marshal(map[string]any{"plaintext": plaintext})
+	mock.sendToClient(phoenixMessage{Topic: "plugin:lobby", Event: "message", Payload: payload})
+}
+
+func unsupportedMessage(id string) map[string]any {
+	return map[string]any{
+		"id":   id,
+		"type": unhandledType,
+		"from": "did:web:bob",
+		"to":   []string{"did:web:alice"},
+		"body": map[string]string{},
+	}
+}
+
+// malformedMessage has a "to" field that is not a list, so parsing fails.
+func malformedMessage(id string) map[string]any {
+	return map[string]any{
+		"id":   id,
+		"type": unhandledType,
+		"from": "did:web:bob",
+		"to":   "did:web:alice",
+	}
+}
+
+func expectReported(t *testing.T, errs chan SDKError, kind ErrorKind) {
+	t.Helper()
+	select {
+	case err := <-errs:
+		if err.Kind != kind {
+			t.Fatalf("reported %v, want %v", err.Kind, kind)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("timeout waiting for %v", kind)
+	}
+}
+
+// receivedEvents waits briefly for the client to finish reacting, then
+// returns the acked IDs and the plaintext of messages sent to the node.
+func receivedEvents(mock *mockPhoenixServer) (acks []string, sent []map[string]json.RawMessage) {
+	time.Sleep(100 * time.Millisecond)
+	for _, msg := range mock.getReceived() {
+		switch msg.Event {
+		case "ack":
+			var p struct {
+				IDs []string `json:"ids"`
+			}
+			json.Unmarshal(msg.Payload, &p)
+			acks = append(acks, p.IDs...)
+		case "message":
+			var p map[string]json.RawMessage
+			json.Unmarshal(msg.Payload, &p)
+			sent = append(sent, p)
+		}
+	}
+	return acks, sent
+}
+
+func TestClient_Unhandled_LeaveByDefault(t *testing.T) {
+	mock, errs := connectUnhandledClient(t, Config{})
+
+	sendInbound(mock, unsupportedMessage("m1"))
+	expectReported(t, errs, ErrNoHandler)
+
+	acks, sent := receivedEvents(mock)
+	if len(acks) != 0 || len(sent) != 0 {
+		t.Errorf("acks = %v, sent = %d; want the message left alone", acks, len(sent))
+	}
+}
+
+func TestClient_Unhandled_AckDrop(t *testing.T) {
+	mock, errs := connectUnhandledClient(t, Config{Unhandled: UnhandledAckDrop})
+
+	sendInbound(mock, unsupportedMessage("m1"))
+	expectReported(t, errs, ErrNoHandler)
+	sendInbound(mock, malformedMessage("m2"))
+	expectReported(t, errs, ErrParseFailure)
+
+	acks, sent := receivedEvents(mock)
+	if !slices.Equal(acks, []string{"m1", "m2"}) {
+		t.Errorf("acks = %v, want [m1 m2]", acks)
+	}
+	if len(sent) != 0 {
+		t.Errorf("sent %d messages, want none", len(sent))
+	}
+}
+
+func TestClient_Unhandled_AckReply(t *testing.T) {
+	mock, errs := connectUnhandledClient(t, Config{Unhandled: UnhandledAckReply})
+
+	sendInbound(mock, unsupportedMessage("m1"))
+	expectReported(t, errs, ErrNoHandler)
+	sendInbound(mock, malformedMessage("m2"))
+	expectReported(t, errs, ErrParseFailure)
+
+	acks, sent := receivedEvents(mock)
+	if !slices.Equal(acks, []string{"m1", "m2"}) {
+		t.Errorf("acks = %v, want [m1 m2]", acks)
+	}
+	if len(sent) != 2 {
+		t.Fatalf("sent %d messages, want 2 problem reports", len(sent))
+	}
+	for i, wantThid := range []string{"m1", "m2"} {
+		var msgType, thid string
+		var body ProblemReportError
+		var to []string
+		json.Unmarshal(sent[i]["type"], &msgType)
+		json.Unmarshal(sent[i]["thid"], &thid)
+		json.Unmarshal(sent[i]["to"], &to)
+		json.Unmarshal(sent[i]["body"], &body)
+		if msgType != "https://didcomm.org/report-problem/2.0/problem-report" || body.Code != "e.p.msg.unsupported" {
+			t.Errorf("sent[%d] = %s %+v, want an e.p.msg.unsupported problem report", i, msgType, body)
+		}
+		if thid != wantThid || !slices.Equal(to, []string{"did:web:bob"}) {
+			t.Errorf("sent[%d] thid = %q to = %v, want %q to did:web:bob", i, thid, to, wantThid)
+		}
+	}
+}
+
+func TestClient_Unhandled_DeadLetter(t *testing.T) {
+	sink := NewMemoryDeadLetterSink()
+	mock, errs := connectUnhandledClient(t, Config{Unhandled: UnhandledDeadLetter, DeadLetters: sink})
+
+	sendInbound(mock, unsupportedMessage("m1"))
+	expectReported(t, errs, ErrNoHandler)
+	sendInbound(mock, malformedMessage("m2"))
+	expectReported(t, errs, ErrParseFailure)
+
+	acks, sent := receivedEvents(mock)
+	if !slices.Equal(acks, []string{"m1", "m2"}) {
+		t.Errorf("acks = %v, want [m1 m2]", acks)
+	}
+	if len(sent) != 0 {
+		t.Errorf("sent %d messages, want none", len(sent))
+	}
+
+	letters, _ := sink.List(context.Background())
+	if len(letters) != 2 {
+		t.Fatalf("dead letters = %d, want 2", len(letters))
+	}
+	if dl := letters[0]; dl.MessageID != "m1" || dl.Type != unhandledType || dl.From != "did:web:bob" || dl.Kind != ErrNoHandler {
+		t.Errorf("letters[0] = %+v", dl)
+	}
+	if dl := letters[1]; dl.MessageID != "m2" || dl.Kind != ErrParseFailure || dl.Error == "" {
+		t.Errorf("letters[1] = %+v, want a parse failure with its error", dl)
+	}
+	var env struct {
+		Plaintext struct {
+			ID string `json:"id"`
+		} `json:"plaintext"`
+	}
+	if err := json.Unmarshal(letters[1].Payload, &env); err != nil || env.Plaintext.ID != "m2" {
+		t.Errorf("Payload = %s, want the raw envelope", letters[1].Payload)
+	}
+}
+
+func TestClient_Unhandled_DeadLetterFails(t *testing.T) {
+	sink := failingSink{NewMemoryDeadLetterSink(), errors.New("disk full")}
+	mock, errs := connectUnhandledClient(t, Config{Unhandled: UnhandledDeadLetter, DeadLetters: sink})
+
+	sendInbound(mock, unsupportedMessage("m1"))
+	expectReported(t, errs, ErrNoHandler)
+	expectReported(t, errs, ErrDeadLetter)
+
+	if acks, _ := receivedEvents(mock); len(acks) != 0 {
+		t.Errorf("acks = %v, want none when the sink fails", acks)
+	}
+}